@@ -0,0 +1,185 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package automata
+
+import (
+	"sort"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// DFA is a deterministic finite automaton.
+// Missing transitions lead to an implicit rejecting state.
+type DFA struct {
+	Start       int
+	Accepting   []bool
+	Transitions []map[rune]int
+
+	// States holds the NFA states represented by each DFA state.
+	// It may be nil or short for hand-built automata.
+	States []*sparsebitvector.SparseBitVector
+}
+
+// Match returns true iff d accepts input.
+func (d *DFA) Match(input string) bool {
+	state := d.Start
+	for _, symbol := range input {
+		next, ok := d.Transitions[state][symbol]
+		if !ok {
+			return false
+		}
+		state = next
+	}
+	return d.Accepting[state]
+}
+
+// alphabet returns the symbols used by transitions in ascending order.
+func (d *DFA) alphabet() []rune {
+	symbols := map[rune]bool{}
+	for _, transitions := range d.Transitions {
+		for symbol := range transitions {
+			symbols[symbol] = true
+		}
+	}
+	result := []rune{}
+	for symbol := range symbols {
+		result = append(result, symbol)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// Minimize returns the minimal DFA accepting the same language as d,
+// computed by Hopcroft's partition refinement.
+// Blocks of the partition are SparseBitVectors of state numbers and are
+// split with bulk intersections against the preimage of each splitter.
+func (d *DFA) Minimize() *DFA {
+	alphabet := d.alphabet()
+	dead := len(d.Transitions)
+	states := dead + 1
+
+	// inverse[c][q] holds the states with a transition to q on alphabet[c]
+	inverse := make([][]*sparsebitvector.SparseBitVector, len(alphabet))
+	for c, symbol := range alphabet {
+		inverse[c] = make([]*sparsebitvector.SparseBitVector, states)
+		for q := range inverse[c] {
+			inverse[c][q] = sparsebitvector.New()
+		}
+		for q := 0; q < states; q++ {
+			target := dead
+			if q != dead {
+				if next, ok := d.Transitions[q][symbol]; ok {
+					target = next
+				}
+			}
+			inverse[c][target].Set(sparsebitvector.KeyType(q))
+		}
+	}
+
+	accepting, rejecting := sparsebitvector.New(), sparsebitvector.New()
+	for q := 0; q < states; q++ {
+		if q != dead && d.Accepting[q] {
+			accepting.Set(sparsebitvector.KeyType(q))
+		} else {
+			rejecting.Set(sparsebitvector.KeyType(q))
+		}
+	}
+	partition := []*sparsebitvector.SparseBitVector{rejecting}
+	if accepting.Count() != 0 {
+		partition = append(partition, accepting)
+	}
+
+	// the worklist holds (block, symbol) splitters
+	type splitter struct{ block, symbol int }
+	pending := map[splitter]bool{}
+	work := []splitter{}
+	push := func(s splitter) {
+		if !pending[s] {
+			pending[s] = true
+			work = append(work, s)
+		}
+	}
+	smallest := 0
+	if len(partition) == 2 && accepting.Count() < rejecting.Count() {
+		smallest = 1
+	}
+	for c := range alphabet {
+		push(splitter{smallest, c})
+	}
+
+	for len(work) > 0 {
+		s := work[len(work)-1]
+		work = work[:len(work)-1]
+		delete(pending, s)
+
+		sources := []*sparsebitvector.SparseBitVector{}
+		for q := range partition[s.block].Iterate() {
+			sources = append(sources, inverse[s.symbol][q])
+		}
+		preimage := sparsebitvector.UnionAll(sources...)
+
+		for i, n := 0, len(partition); i < n; i++ {
+			inside := sparsebitvector.IntersectAll(partition[i], preimage)
+			if inside.Count() == 0 || inside.Count() == partition[i].Count() {
+				continue
+			}
+			outside := sparsebitvector.New()
+			outside.SetToDifference(partition[i], preimage)
+			partition[i] = inside
+			partition = append(partition, outside)
+
+			split := len(partition) - 1
+			for c := range alphabet {
+				if pending[splitter{i, c}] {
+					push(splitter{split, c})
+				} else if inside.Count() <= outside.Count() {
+					push(splitter{i, c})
+				} else {
+					push(splitter{split, c})
+				}
+			}
+		}
+	}
+
+	// number the blocks, omitting the one equivalent to the dead state
+	block := make([]int, states)
+	for i, members := range partition {
+		for q := range members.Iterate() {
+			block[q] = i
+		}
+	}
+	number := make([]int, len(partition))
+	result := &DFA{}
+	for i, members := range partition {
+		if i == block[dead] {
+			number[i] = -1
+			continue
+		}
+		number[i] = len(result.Transitions)
+		result.Transitions = append(result.Transitions, make(map[rune]int))
+		result.Accepting = append(result.Accepting, members.IntersectionSize(accepting) != 0)
+
+		sets := []*sparsebitvector.SparseBitVector{}
+		for q := range members.Iterate() {
+			if q < sparsebitvector.KeyType(len(d.States)) && d.States[q] != nil {
+				sets = append(sets, d.States[q])
+			}
+		}
+		result.States = append(result.States, sparsebitvector.UnionAll(sets...))
+	}
+	for q, transitions := range d.Transitions {
+		for symbol, target := range transitions {
+			if to := number[block[target]]; to != -1 {
+				result.Transitions[number[block[q]]][symbol] = to
+			}
+		}
+	}
+
+	// a language-empty DFA keeps a single rejecting state
+	if result.Start = number[block[d.Start]]; result.Start == -1 {
+		result = &DFA{Accepting: []bool{false}, Transitions: []map[rune]int{{}}, States: []*sparsebitvector.SparseBitVector{sparsebitvector.New()}}
+	}
+	return result
+}
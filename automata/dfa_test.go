@@ -0,0 +1,63 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package automata
+
+import "testing"
+
+func TestMinimize(t *testing.T) {
+	d := thompson().DFA()
+	m := d.Minimize()
+	if len(m.Transitions) != 4 || len(m.States) != 4 {
+		t.Error("incorrect state count", len(m.Transitions))
+	}
+	for _, input := range []string{"", "a", "b", "ab", "abb", "aabb", "babb", "abba", "abbabb", "bbbbb", "c"} {
+		if m.Match(input) != d.Match(input) {
+			t.Error("incorrect match", input)
+		}
+	}
+	if again := m.Minimize(); len(again.Transitions) != 4 {
+		t.Error("minimization not idempotent", len(again.Transitions))
+	}
+}
+
+func TestMinimizeDeadStates(t *testing.T) {
+	// states which cannot reach an accepting state are removed
+	n := NewNFA()
+	accept, trap := n.AddState(), n.AddState()
+	n.AddTransition(0, 'a', accept)
+	n.AddTransition(0, 'b', trap)
+	n.AddTransition(trap, 'a', trap)
+	n.SetAccepting(accept)
+
+	m := n.DFA().Minimize()
+	if len(m.Transitions) != 2 {
+		t.Error("incorrect state count", len(m.Transitions))
+	}
+	if !m.Match("a") || m.Match("b") || m.Match("ba") {
+		t.Error("incorrect match")
+	}
+
+	// a language-empty automaton collapses to a single rejecting state
+	empty := NewNFA()
+	empty.AddTransition(0, 'a', empty.AddState())
+	if m := empty.DFA().Minimize(); len(m.Transitions) != 1 || m.Match("") || m.Match("a") {
+		t.Error("incorrect empty automaton", m.Transitions)
+	}
+}
+
+func TestMinimizeHandBuilt(t *testing.T) {
+	// a DFA without States, with two equivalent accepting states
+	d := &DFA{
+		Accepting:   []bool{false, true, true},
+		Transitions: []map[rune]int{{'a': 1, 'b': 2}, {'a': 1}, {'a': 2}},
+	}
+	m := d.Minimize()
+	if len(m.Transitions) != 2 || len(m.States) != 2 || m.States[0].Count() != 0 {
+		t.Error("incorrect minimized automaton", m.Transitions, m.States)
+	}
+	if !m.Match("a") || !m.Match("baa") || m.Match("") || m.Match("ab") {
+		t.Error("incorrect match")
+	}
+}
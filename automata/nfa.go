@@ -0,0 +1,158 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+// Package automata builds and minimizes finite automata
+// whose NFA state sets are SparseBitVectors.
+package automata
+
+import (
+	"sort"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// NFA is a nondeterministic finite automaton with epsilon transitions.
+// Its states are numbered from 0, and state 0 is the start state.
+type NFA struct {
+	epsilon     []*sparsebitvector.SparseBitVector
+	transitions []map[rune]*sparsebitvector.SparseBitVector
+	accepting   *sparsebitvector.SparseBitVector
+}
+
+// NewNFA creates an NFA containing only its start state.
+func NewNFA() *NFA {
+	n := &NFA{accepting: sparsebitvector.New()}
+	n.AddState()
+	return n
+}
+
+// AddState adds a state and returns its number.
+func (n *NFA) AddState() int {
+	n.epsilon = append(n.epsilon, sparsebitvector.New())
+	n.transitions = append(n.transitions, make(map[rune]*sparsebitvector.SparseBitVector))
+	return len(n.epsilon) - 1
+}
+
+// AddTransition adds a transition on symbol from one state to another.
+func (n *NFA) AddTransition(from int, symbol rune, to int) {
+	targets, ok := n.transitions[from][symbol]
+	if !ok {
+		targets = sparsebitvector.New()
+		n.transitions[from][symbol] = targets
+	}
+	targets.Set(sparsebitvector.KeyType(to))
+}
+
+// AddEpsilon adds an epsilon transition from one state to another.
+func (n *NFA) AddEpsilon(from, to int) {
+	n.epsilon[from].Set(sparsebitvector.KeyType(to))
+}
+
+// SetAccepting marks a state as accepting.
+func (n *NFA) SetAccepting(state int) {
+	n.accepting.Set(sparsebitvector.KeyType(state))
+}
+
+// EpsilonClosure returns the states reachable from states by epsilon transitions, including states.
+func (n *NFA) EpsilonClosure(states *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	closure := sparsebitvector.UnionAll(states)
+	frontier := closure
+	for frontier.Count() != 0 {
+		targets := []*sparsebitvector.SparseBitVector{}
+		for state := range frontier.Iterate() {
+			targets = append(targets, n.epsilon[state])
+		}
+		frontier = sparsebitvector.UnionAll(targets...)
+		frontier.IntersectWithComplement(closure)
+		closure.UnionWith(frontier)
+	}
+	return closure
+}
+
+// Move returns the states reachable from states by a single transition on symbol.
+func (n *NFA) Move(states *sparsebitvector.SparseBitVector, symbol rune) *sparsebitvector.SparseBitVector {
+	targets := []*sparsebitvector.SparseBitVector{}
+	for state := range states.Iterate() {
+		if vec, ok := n.transitions[state][symbol]; ok {
+			targets = append(targets, vec)
+		}
+	}
+	return sparsebitvector.UnionAll(targets...)
+}
+
+// Alphabet returns the symbols used by transitions in ascending order.
+func (n *NFA) Alphabet() []rune {
+	symbols := map[rune]bool{}
+	for _, transitions := range n.transitions {
+		for symbol := range transitions {
+			symbols[symbol] = true
+		}
+	}
+	result := []rune{}
+	for symbol := range symbols {
+		result = append(result, symbol)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// interner numbers distinct state sets.
+type interner struct {
+	sets     *sparsebitvector.Interner
+	ids      map[*sparsebitvector.SparseBitVector]int
+	interned []*sparsebitvector.SparseBitVector
+}
+
+// intern returns the number of set, and true iff it was not seen before.
+func (in *interner) intern(set *sparsebitvector.SparseBitVector) (int, bool) {
+	canonical := in.sets.Intern(set)
+	if id, ok := in.ids[canonical]; ok {
+		return id, false
+	}
+	id := len(in.interned)
+	in.ids[canonical] = id
+	in.interned = append(in.interned, canonical)
+	return id, true
+}
+
+// DFA converts n into a DFA using the subset construction.
+// Each DFA state corresponds to a distinct, interned set of NFA states.
+// Empty sets are omitted, so a DFA state may lack transitions on some symbols.
+func (n *NFA) DFA() *DFA {
+	alphabet := n.Alphabet()
+	in := &interner{sets: sparsebitvector.NewInterner(), ids: make(map[*sparsebitvector.SparseBitVector]int)}
+	d := &DFA{}
+
+	start, _ := in.intern(n.EpsilonClosure(sparsebitvector.New(0)))
+	for work := []int{start}; len(work) > 0; {
+		id := work[len(work)-1]
+		work = work[:len(work)-1]
+		for len(d.Transitions) <= id {
+			d.Transitions = append(d.Transitions, make(map[rune]int))
+		}
+
+		for _, symbol := range alphabet {
+			target := n.EpsilonClosure(n.Move(in.interned[id], symbol))
+			if target.Count() == 0 {
+				continue
+			}
+			targetID, added := in.intern(target)
+			if added {
+				work = append(work, targetID)
+			}
+			d.Transitions[id][symbol] = targetID
+		}
+	}
+
+	for len(d.Transitions) < len(in.interned) {
+		d.Transitions = append(d.Transitions, make(map[rune]int))
+	}
+	d.States = in.interned
+	d.Accepting = make([]bool, len(in.interned))
+	for id, set := range in.interned {
+		d.Accepting[id] = set.IntersectionSize(n.accepting) != 0
+	}
+	d.Start = start
+	return d
+}
@@ -0,0 +1,77 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package automata
+
+import (
+	"testing"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// thompson returns the textbook Thompson NFA for (a|b)*abb.
+func thompson() *NFA {
+	n := NewNFA()
+	for n.AddState() != 10 {
+	}
+	n.AddEpsilon(0, 1)
+	n.AddEpsilon(0, 7)
+	n.AddEpsilon(1, 2)
+	n.AddEpsilon(1, 4)
+	n.AddTransition(2, 'a', 3)
+	n.AddTransition(4, 'b', 5)
+	n.AddEpsilon(3, 6)
+	n.AddEpsilon(5, 6)
+	n.AddEpsilon(6, 1)
+	n.AddEpsilon(6, 7)
+	n.AddTransition(7, 'a', 8)
+	n.AddTransition(8, 'b', 9)
+	n.AddTransition(9, 'b', 10)
+	n.SetAccepting(10)
+	return n
+}
+
+func TestEpsilonClosure(t *testing.T) {
+	n := thompson()
+	if c := n.EpsilonClosure(sparsebitvector.New(0)); c.String() != "[0 1 2 4 7]" {
+		t.Error("incorrect closure", c)
+	}
+	if c := n.EpsilonClosure(n.Move(sparsebitvector.New(0, 1, 2, 4, 7), 'a')); c.String() != "[1 2 3 4 6 7 8]" {
+		t.Error("incorrect closure", c)
+	}
+	if m := n.Move(sparsebitvector.New(0, 1), 'a'); m.Count() != 0 {
+		t.Error("unexpected move", m)
+	}
+	if a := n.Alphabet(); len(a) != 2 || a[0] != 'a' || a[1] != 'b' {
+		t.Error("incorrect alphabet", a)
+	}
+}
+
+func TestSubsetConstruction(t *testing.T) {
+	d := thompson().DFA()
+	if len(d.Transitions) != 5 || len(d.States) != 5 {
+		t.Error("incorrect state count", len(d.Transitions))
+	}
+	if d.States[d.Start].String() != "[0 1 2 4 7]" {
+		t.Error("incorrect start state", d.States[d.Start])
+	}
+	accepting := 0
+	for id, ok := range d.Accepting {
+		if ok {
+			accepting++
+			if !d.States[id].Test(10) {
+				t.Error("incorrect accepting state", d.States[id])
+			}
+		}
+	}
+	if accepting != 1 {
+		t.Error("incorrect accepting count", accepting)
+	}
+
+	for input, expected := range map[string]bool{"abb": true, "aabb": true, "babb": true, "ab": false, "abba": false, "": false, "abc": false} {
+		if d.Match(input) != expected {
+			t.Error("incorrect match", input)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// BitVector is a growable dense bit vector with the same operations as SparseBitVector,
+// for keys drawn from a small, densely populated range.
+// It grows to hold its largest true bit, so it is unsuitable for large keys.
+type BitVector struct {
+	words []uint64
+	count int
+}
+
+// NewBitVector creates a BitVector, optionally initialized by set.
+func NewBitVector(set ...KeyType) *BitVector {
+	result := new(BitVector)
+	for _, i := range set {
+		result.Set(i)
+	}
+	return result
+}
+
+// NewBitVectorOf creates a BitVector with the true bits of sbv.
+func NewBitVectorOf(sbv *SparseBitVector) *BitVector {
+	result := new(BitVector)
+	result.UnionWithSparse(sbv)
+	return result
+}
+
+// word returns the word holding key, or 0 if it is beyond the end of bv.
+func (bv *BitVector) word(w KeyType) uint64 {
+	if w < KeyType(len(bv.words)) {
+		return bv.words[w]
+	}
+	return 0
+}
+
+// grow extends bv to hold n words.
+func (bv *BitVector) grow(n KeyType) {
+	if n > KeyType(len(bv.words)) {
+		bv.words = append(bv.words, make([]uint64, n-KeyType(len(bv.words)))...)
+	}
+}
+
+// block returns the bits of block index in the layout of a SparseBitVector element.
+func (bv *BitVector) block(index KeyType) FiniteBitVector {
+	block := FiniteBitVector{}
+	for w := range block {
+		block[w] = elementwordtype(bv.word(index*wordsperelement + KeyType(w)))
+	}
+	return block
+}
+
+// Set sets a particular bit to true.
+// It returns true iff the bit was changed.
+func (bv *BitVector) Set(key KeyType) bool {
+	w, bit := key/bitsperword, uint64(1)<<(key%bitsperword)
+	bv.grow(w + 1)
+	if bv.words[w]&bit != 0 {
+		return false
+	}
+	bv.words[w] |= bit
+	bv.count++
+	return true
+}
+
+// Unset sets a particular bit to false.
+// It returns true iff the bit was changed.
+func (bv *BitVector) Unset(key KeyType) bool {
+	w, bit := key/bitsperword, uint64(1)<<(key%bitsperword)
+	if bv.word(w)&bit == 0 {
+		return false
+	}
+	bv.words[w] &^= bit
+	bv.count--
+	return true
+}
+
+// Clear sets all bits to false.
+// It returns true iff bv changed, along with the number of bits removed.
+func (bv *BitVector) Clear() (bool, int) {
+	removed := bv.count
+	bv.words = nil
+	bv.count = 0
+	return removed != 0, removed
+}
+
+// Count returns the number of distinct bits that are true.
+func (bv *BitVector) Count() int {
+	return bv.count
+}
+
+// Test checks whether a particular bit is true.
+func (bv *BitVector) Test(key KeyType) bool {
+	return bv.word(key/bitsperword)&(1<<(key%bitsperword)) != 0
+}
+
+// TestAndSet sets a bit to true and returns true iff it was changed.
+func (bv *BitVector) TestAndSet(key KeyType) bool {
+	return bv.Set(key)
+}
+
+// NextSet returns the first true bit at or after key, and false if there is none.
+func (bv *BitVector) NextSet(key KeyType) (KeyType, bool) {
+	w := key / bitsperword
+	if w >= KeyType(len(bv.words)) {
+		return 0, false
+	}
+	if word := bv.words[w] >> (key % bitsperword); word != 0 {
+		return key + KeyType(bits.TrailingZeros64(word)), true
+	}
+	for w++; w < KeyType(len(bv.words)); w++ {
+		if bv.words[w] != 0 {
+			return w*bitsperword + KeyType(bits.TrailingZeros64(bv.words[w])), true
+		}
+	}
+	return 0, false
+}
+
+// PrevSet returns the last true bit at or before key, and false if there is none.
+func (bv *BitVector) PrevSet(key KeyType) (KeyType, bool) {
+	w := key / bitsperword
+	if w >= KeyType(len(bv.words)) {
+		if len(bv.words) == 0 {
+			return 0, false
+		}
+		w = KeyType(len(bv.words) - 1)
+		key = w*bitsperword + bitsperword - 1
+	}
+	if word := bv.words[w] << (bitsperword - 1 - key%bitsperword); word != 0 {
+		return key - KeyType(bits.LeadingZeros64(word)), true
+	}
+	for w > 0 {
+		w--
+		if bv.words[w] != 0 {
+			return w*bitsperword + bitsperword - 1 - KeyType(bits.LeadingZeros64(bv.words[w])), true
+		}
+	}
+	return 0, false
+}
+
+// Equals returns true iff bv and bv2 contain equivalent true bits.
+func (bv *BitVector) Equals(bv2 *BitVector) bool {
+	if bv.count != bv2.count {
+		return false
+	}
+	n := len(bv.words)
+	if len(bv2.words) > n {
+		n = len(bv2.words)
+	}
+	for w := KeyType(0); w < KeyType(n); w++ {
+		if bv.word(w) != bv2.word(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains returns true iff bv contains all true bits of bv2.
+func (bv *BitVector) Contains(bv2 *BitVector) bool {
+	for w, word := range bv2.words {
+		if word&^bv.word(KeyType(w)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// UnionAndIntersectionSize returns the number of true bits of the union and intersection with bv2.
+func (bv *BitVector) UnionAndIntersectionSize(bv2 *BitVector) (int, int) {
+	intersection := 0
+	for w := 0; w < len(bv.words) && w < len(bv2.words); w++ {
+		intersection += bits.OnesCount64(bv.words[w] & bv2.words[w])
+	}
+	return bv.count + bv2.count - intersection, intersection
+}
+
+// UnionSize returns the number of true bits of the union with bv2.
+func (bv *BitVector) UnionSize(bv2 *BitVector) int {
+	u, _ := bv.UnionAndIntersectionSize(bv2)
+	return u
+}
+
+// IntersectionSize returns the number of true bits of the intersection with bv2.
+func (bv *BitVector) IntersectionSize(bv2 *BitVector) int {
+	_, i := bv.UnionAndIntersectionSize(bv2)
+	return i
+}
+
+// UnionWith sets bv to the union of itself and bv2.
+// It returns true iff bv changed, along with the number of bits added.
+func (bv *BitVector) UnionWith(bv2 *BitVector) (bool, int) {
+	bv.grow(KeyType(len(bv2.words)))
+	added := 0
+	for w, word := range bv2.words {
+		added += bits.OnesCount64(word &^ bv.words[w])
+		bv.words[w] |= word
+	}
+	bv.count += added
+	return added != 0, added
+}
+
+// IntersectWith sets bv to the intersection of itself and bv2.
+// It returns true iff bv changed, along with the number of bits removed.
+func (bv *BitVector) IntersectWith(bv2 *BitVector) (bool, int) {
+	removed := 0
+	for w, word := range bv.words {
+		removed += bits.OnesCount64(word &^ bv2.word(KeyType(w)))
+		bv.words[w] = word & bv2.word(KeyType(w))
+	}
+	bv.count -= removed
+	return removed != 0, removed
+}
+
+// IntersectWithComplement sets bv to the intersection of itself and the inverse of bv2.
+// It returns true iff bv changed, along with the number of bits removed.
+func (bv *BitVector) IntersectWithComplement(bv2 *BitVector) (bool, int) {
+	removed := 0
+	for w, word := range bv.words {
+		removed += bits.OnesCount64(word & bv2.word(KeyType(w)))
+		bv.words[w] = word &^ bv2.word(KeyType(w))
+	}
+	bv.count -= removed
+	return removed != 0, removed
+}
+
+// UnionWithSparse sets bv to the union of itself and sbv.
+// It returns true iff bv changed, along with the number of bits added.
+func (bv *BitVector) UnionWithSparse(sbv *SparseBitVector) (bool, int) {
+	added := 0
+	sbv.blocks(func(index KeyType, vec *FiniteBitVector) {
+		bv.grow((index + 1) * wordsperelement)
+		for w, word := range vec {
+			dst := &bv.words[index*wordsperelement+KeyType(w)]
+			added += bits.OnesCount64(uint64(word) &^ *dst)
+			*dst |= uint64(word)
+		}
+	})
+	bv.count += added
+	return added != 0, added
+}
+
+// IntersectWithSparse sets bv to the intersection of itself and sbv.
+// It returns true iff bv changed, along with the number of bits removed.
+func (bv *BitVector) IntersectWithSparse(sbv *SparseBitVector) (bool, int) {
+	removed := 0
+	zero := func(from, to KeyType) {
+		for w := from; w < to && w < KeyType(len(bv.words)); w++ {
+			removed += bits.OnesCount64(bv.words[w])
+			bv.words[w] = 0
+		}
+	}
+	next := KeyType(0) // first word not yet intersected
+	sbv.blocks(func(index KeyType, vec *FiniteBitVector) {
+		first := index * wordsperelement
+		if first >= KeyType(len(bv.words)) {
+			return
+		}
+		zero(next, first)
+		for w, word := range vec {
+			if first+KeyType(w) < KeyType(len(bv.words)) {
+				dst := &bv.words[first+KeyType(w)]
+				removed += bits.OnesCount64(*dst &^ uint64(word))
+				*dst &= uint64(word)
+			}
+		}
+		next = first + wordsperelement
+	})
+	zero(next, KeyType(len(bv.words)))
+	bv.count -= removed
+	return removed != 0, removed
+}
+
+// UnionWithDense sets sbv to the union of itself and bv.
+// It returns true iff sbv changed, along with the number of bits added.
+func (sbv *SparseBitVector) UnionWithDense(bv *BitVector) (bool, int) {
+	sbv.pageAll()
+	defer sbv.enforce()
+	added := 0
+	for index := KeyType(0); index*wordsperelement < KeyType(len(bv.words)); index++ {
+		block := bv.block(index)
+		if block.Count() == 0 {
+			continue
+		}
+		e := sbv.search(index)
+		if e == nil || e.index != index {
+			before := sbv.arrayBlock(index)
+			after := before
+			after.UnionWith(&block)
+			added += after.Count() - before.Count()
+			sbv.rehash(index, &before, &after)
+			sbv.storeBlock(index, &after)
+			continue
+		}
+		before := e.FiniteBitVector
+		e.UnionWith(&block)
+		added += e.Count() - before.Count()
+		sbv.rehash(index, &before, &e.FiniteBitVector)
+	}
+	sbv.count += added
+	return added != 0, added
+}
+
+// IntersectWithDense sets sbv to the intersection of itself and bv.
+// It returns true iff sbv changed, along with the number of bits removed.
+func (sbv *SparseBitVector) IntersectWithDense(bv *BitVector) (bool, int) {
+	sbv.pageAll()
+	defer sbv.enforce()
+	removed := sbv.filterArray(func(index KeyType, vec *FiniteBitVector) {
+		block := bv.block(index)
+		vec.IntersectWith(&block)
+	}, nil)
+	for e := sbv.start; e != nil; e = e.next {
+		block := bv.block(e.index)
+		before := e.FiniteBitVector
+		e.IntersectWith(&block)
+		removed += before.Count() - e.Count()
+		sbv.rehash(e.index, &before, &e.FiniteBitVector)
+		sbv.fit(e)
+	}
+	sbv.count -= removed
+	return removed != 0, removed
+}
+
+// Sparse returns a new SparseBitVector with the true bits of bv.
+func (bv *BitVector) Sparse() *SparseBitVector {
+	result := New()
+	bv.Blocks(func(index KeyType, vec FiniteBitVector) {
+		result.push(index, &vec)
+	})
+	return result
+}
+
+// Blocks calls fn with the index and bits of each non-empty block of ElementSize bits in ascending order.
+func (bv *BitVector) Blocks(fn func(index KeyType, vec FiniteBitVector)) {
+	for index := KeyType(0); index*wordsperelement < KeyType(len(bv.words)); index++ {
+		if block := bv.block(index); block.Count() != 0 {
+			fn(index, block)
+		}
+	}
+}
+
+// Iterate returns a channel which publishes all true bits in ascending order.
+// The behaviour is undefined for bits modified while iterating.
+func (bv *BitVector) Iterate() <-chan KeyType {
+	c := make(chan KeyType)
+	go func(c chan<- KeyType) {
+		for w, word := range bv.words {
+			for ; word != 0; word &= word - 1 {
+				c <- KeyType(w)*bitsperword + KeyType(bits.TrailingZeros64(word))
+			}
+		}
+		close(c)
+	}(c)
+	return c
+}
+
+func (bv *BitVector) String() string {
+	result := []KeyType{}
+	for i := range bv.Iterate() {
+		result = append(result, i)
+	}
+	return fmt.Sprint(result)
+}
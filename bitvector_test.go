@@ -0,0 +1,131 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBitVector(t *testing.T) {
+	bv := NewBitVector(1, 64, 300)
+	if !bv.Set(2) || bv.Set(2) || bv.TestAndSet(64) || bv.Count() != 4 {
+		t.Error("unexpected set", bv.Count())
+	}
+	if !bv.Test(300) || bv.Test(3) || bv.Test(1<<20) {
+		t.Error("incorrect test")
+	}
+	if !bv.Unset(1) || bv.Unset(1) || bv.Unset(1<<20) || bv.String() != "[2 64 300]" {
+		t.Error("unexpected unset", bv)
+	}
+	if next, ok := bv.NextSet(65); !ok || next != 300 {
+		t.Error("incorrect next", next, ok)
+	}
+	if _, ok := bv.NextSet(301); ok {
+		t.Error("unexpected next")
+	}
+	if prev, ok := bv.PrevSet(299); !ok || prev != 64 {
+		t.Error("incorrect prev", prev, ok)
+	}
+	if prev, ok := bv.PrevSet(1 << 20); !ok || prev != 300 {
+		t.Error("incorrect prev", prev, ok)
+	}
+	if _, ok := bv.PrevSet(1); ok {
+		t.Error("unexpected prev")
+	}
+	if changed, removed := bv.Clear(); !changed || removed != 3 || bv.Count() != 0 {
+		t.Error("unexpected clear", changed, removed)
+	}
+}
+
+func TestBitVectorOperations(t *testing.T) {
+	a := NewBitVector(1, 2, 3, 500)
+	b := NewBitVector(2, 3, 4)
+	if !a.Equals(NewBitVector(500, 3, 2, 1)) || a.Equals(b) {
+		t.Error("incorrect equality")
+	}
+	b.Set(1000)
+	b.Unset(1000)
+	if !NewBitVector(2, 3, 4).Equals(b) {
+		t.Error("trailing words affected equality")
+	}
+	if u, i := a.UnionAndIntersectionSize(b); u != 5 || i != 2 {
+		t.Error("incorrect sizes", u, i)
+	}
+	if !a.Contains(NewBitVector(1, 500)) || a.Contains(b) {
+		t.Error("incorrect containment")
+	}
+
+	c := NewBitVector(1, 2, 3, 500)
+	if changed, added := c.UnionWith(b); !changed || added != 1 || c.String() != "[1 2 3 4 500]" {
+		t.Error("incorrect union", c)
+	}
+	if changed, removed := c.IntersectWith(b); !changed || removed != 2 || c.String() != "[2 3 4]" {
+		t.Error("incorrect intersection", c)
+	}
+	if changed, removed := a.IntersectWithComplement(b); !changed || removed != 2 || a.String() != "[1 500]" {
+		t.Error("incorrect difference", a)
+	}
+}
+
+func TestBitVectorSparse(t *testing.T) {
+	bv := NewBitVector(1, 200, 201)
+	sbv := New(1, 5, 1000)
+
+	if changed, added := bv.UnionWithSparse(sbv); !changed || added != 2 || bv.String() != "[1 5 200 201 1000]" {
+		t.Error("incorrect dense union", bv)
+	}
+	if changed, removed := bv.IntersectWithSparse(New(5, 201, 5000)); !changed || removed != 3 || bv.String() != "[5 201]" {
+		t.Error("incorrect dense intersection", bv)
+	}
+	if changed, added := sbv.UnionWithDense(bv); !changed || added != 1 || sbv.String() != "[1 5 201 1000]" {
+		t.Error("incorrect sparse union", sbv)
+	}
+	if changed, removed := sbv.IntersectWithDense(bv); !changed || removed != 2 || sbv.String() != "[5 201]" {
+		t.Error("incorrect sparse intersection", sbv)
+	}
+	if !bv.Sparse().Equals(sbv) || !NewBitVectorOf(sbv).Equals(bv) {
+		t.Error("incorrect conversion")
+	}
+}
+
+func TestBitVectorRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() (*BitVector, *SparseBitVector) {
+		bv, sbv := NewBitVector(), New()
+		for i := r.Intn(100); i > 0; i-- {
+			key := KeyType(r.Intn(2000))
+			bv.Set(key)
+			sbv.Set(key)
+		}
+		return bv, sbv
+	}
+	for i := 0; i < 200; i++ {
+		bv1, sbv1 := random()
+		bv2, sbv2 := random()
+
+		u, n := bv1.UnionAndIntersectionSize(bv2)
+		u2, n2 := sbv1.UnionAndIntersectionSize(sbv2)
+		if u != u2 || n != n2 || bv1.Contains(bv2) != sbv1.Contains(sbv2) {
+			t.Error("incorrect sizes", u, n, u2, n2)
+		}
+
+		dense, sparse := NewBitVectorOf(sbv1), sbv1.clone()
+		_, c1 := dense.IntersectWithSparse(sbv2)
+		_, c2 := sparse.IntersectWithDense(bv2)
+		_, c3 := sbv1.clone().IntersectWith(sbv2)
+		if c1 != c3 || c2 != c3 || !dense.Sparse().Equals(sparse) {
+			t.Error("incorrect intersection", c1, c2, c3)
+		}
+
+		dense, sparse = NewBitVectorOf(sbv1), sbv1.clone()
+		_, c1 = dense.UnionWithSparse(sbv2)
+		_, c2 = sparse.UnionWithDense(bv2)
+		_, c3 = sbv1.clone().UnionWith(sbv2)
+		if c1 != c3 || c2 != c3 || !dense.Sparse().Equals(sparse) || dense.Count() != sparse.Count() {
+			t.Error("incorrect union", c1, c2, c3)
+		}
+	}
+}
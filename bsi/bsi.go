@@ -0,0 +1,200 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+// Package bsi provides a bit-sliced index of unsigned integer values by row.
+package bsi
+
+import (
+	"math/bits"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// BSI stores a uint64 value per row as one SparseBitVector of rows per value bit.
+// Queries combine the slices with set operations, from the most significant bit down,
+// and take an optional filter restricting the rows considered; nil considers every row.
+type BSI struct {
+	exists *sparsebitvector.SparseBitVector
+	slices []*sparsebitvector.SparseBitVector // slices[i] holds the rows whose value has bit i set
+}
+
+// New creates an empty BSI.
+func New() *BSI {
+	return &BSI{exists: sparsebitvector.New()}
+}
+
+// Set sets the value of row.
+func (b *BSI) Set(row sparsebitvector.KeyType, value uint64) {
+	for len(b.slices) < bits.Len64(value) {
+		b.slices = append(b.slices, sparsebitvector.New())
+	}
+	b.exists.Set(row)
+	for i, slice := range b.slices {
+		if value>>uint(i)&1 == 1 {
+			slice.Set(row)
+		} else {
+			slice.Unset(row)
+		}
+	}
+}
+
+// Get returns the value of row, and false if it has none.
+func (b *BSI) Get(row sparsebitvector.KeyType) (uint64, bool) {
+	if !b.exists.Test(row) {
+		return 0, false
+	}
+	value := uint64(0)
+	for i, slice := range b.slices {
+		if slice.Test(row) {
+			value |= 1 << uint(i)
+		}
+	}
+	return value, true
+}
+
+// Remove removes the value of row.
+// It returns true iff b changed.
+func (b *BSI) Remove(row sparsebitvector.KeyType) bool {
+	for _, slice := range b.slices {
+		slice.Unset(row)
+	}
+	return b.exists.Unset(row)
+}
+
+// Rows returns the rows which have a value.
+// The result is shared with b and must not be modified.
+func (b *BSI) Rows() *sparsebitvector.SparseBitVector {
+	return b.exists
+}
+
+// rows returns a new vector of the rows with a value which pass filter.
+func (b *BSI) rows(filter *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	if filter == nil {
+		return sparsebitvector.UnionAll(b.exists)
+	}
+	return sparsebitvector.IntersectAll(b.exists, filter)
+}
+
+// compare returns the rows passing filter whose values are less than,
+// equal to and greater than value.
+func (b *BSI) compare(value uint64, filter *sparsebitvector.SparseBitVector) (lt, eq, gt *sparsebitvector.SparseBitVector) {
+	lt, eq, gt = sparsebitvector.New(), b.rows(filter), sparsebitvector.New()
+	if bits.Len64(value) > len(b.slices) {
+		// value exceeds every stored value
+		return eq, sparsebitvector.New(), gt
+	}
+	step := sparsebitvector.New()
+	for i := len(b.slices) - 1; i >= 0 && eq.Count() != 0; i-- {
+		if value>>uint(i)&1 == 1 {
+			step.SetToDifference(eq, b.slices[i])
+			lt.UnionWith(step)
+			eq.IntersectWith(b.slices[i])
+		} else {
+			step.SetToIntersection(eq, b.slices[i])
+			gt.UnionWith(step)
+			eq.IntersectWithComplement(b.slices[i])
+		}
+	}
+	return lt, eq, gt
+}
+
+// Equal returns the rows passing filter whose value is value.
+func (b *BSI) Equal(value uint64, filter *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	_, eq, _ := b.compare(value, filter)
+	return eq
+}
+
+// LessThan returns the rows passing filter whose value is less than value.
+func (b *BSI) LessThan(value uint64, filter *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	lt, _, _ := b.compare(value, filter)
+	return lt
+}
+
+// GreaterThan returns the rows passing filter whose value is greater than value.
+func (b *BSI) GreaterThan(value uint64, filter *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	_, _, gt := b.compare(value, filter)
+	return gt
+}
+
+// Between returns the rows passing filter whose value is between lo and hi inclusive.
+func (b *BSI) Between(lo, hi uint64, filter *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	result := b.rows(filter)
+	if lo > hi {
+		result.Clear()
+		return result
+	}
+	result.IntersectWithComplement(b.LessThan(lo, result))
+	result.IntersectWithComplement(b.GreaterThan(hi, result))
+	return result
+}
+
+// Sum returns the sum of the values of the rows passing filter, modulo 2^64,
+// along with the number of such rows.
+func (b *BSI) Sum(filter *sparsebitvector.SparseBitVector) (uint64, int) {
+	rows := b.rows(filter)
+	sum := uint64(0)
+	for i, slice := range b.slices {
+		sum += uint64(slice.IntersectionSize(rows)) << uint(i)
+	}
+	return sum, rows.Count()
+}
+
+// extreme returns the minimum, or the maximum if max is set, of the values of the rows passing filter,
+// and false if no row passes filter.
+func (b *BSI) extreme(filter *sparsebitvector.SparseBitVector, max bool) (uint64, bool) {
+	candidates := b.rows(filter)
+	if candidates.Count() == 0 {
+		return 0, false
+	}
+	value := uint64(0)
+	preferred := sparsebitvector.New()
+	for i := len(b.slices) - 1; i >= 0; i-- {
+		// narrow to the candidates with the preferred bit if there are any
+		if max {
+			preferred.SetToIntersection(candidates, b.slices[i])
+		} else {
+			preferred.SetToDifference(candidates, b.slices[i])
+		}
+		found := preferred.Count() != 0
+		if found {
+			candidates, preferred = preferred, candidates
+		}
+		if found == max {
+			value |= 1 << uint(i)
+		}
+	}
+	return value, true
+}
+
+// Min returns the smallest value of the rows passing filter, and false if no row passes filter.
+func (b *BSI) Min(filter *sparsebitvector.SparseBitVector) (uint64, bool) {
+	return b.extreme(filter, false)
+}
+
+// Max returns the largest value of the rows passing filter, and false if no row passes filter.
+func (b *BSI) Max(filter *sparsebitvector.SparseBitVector) (uint64, bool) {
+	return b.extreme(filter, true)
+}
+
+// TopK returns the k rows passing filter with the largest values,
+// breaking ties at the k'th value by preferring lower rows.
+// Rows are narrowed one slice at a time into those certainly in the result
+// and those still tied, following O'Neil and Quass.
+func (b *BSI) TopK(k int, filter *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	greater, tied := sparsebitvector.New(), b.rows(filter)
+	step := sparsebitvector.New()
+	for i := len(b.slices) - 1; i >= 0 && greater.Count() < k && tied.Count() != 0; i-- {
+		step.SetToIntersection(tied, b.slices[i])
+		if greater.Count()+step.Count() > k {
+			tied, step = step, tied
+		} else {
+			greater.UnionWith(step)
+			tied.IntersectWithComplement(b.slices[i])
+		}
+	}
+	for row, ok := tied.NextSet(0); ok && greater.Count() < k; row, ok = tied.NextSet(row + 1) {
+		greater.Set(row)
+	}
+	return greater
+}
@@ -0,0 +1,144 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package bsi
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+func TestBSI(t *testing.T) {
+	b := New()
+	b.Set(1, 100)
+	b.Set(2, 500)
+	b.Set(3, 250)
+	b.Set(1<<40, 0)
+	b.Set(4, 7)
+	b.Set(4, 100)
+
+	if v, ok := b.Get(4); !ok || v != 100 {
+		t.Error("incorrect value", v, ok)
+	}
+	if _, ok := b.Get(5); ok {
+		t.Error("unexpected value")
+	}
+	if r := b.Equal(100, nil); r.String() != "[1 4]" {
+		t.Error("incorrect equal", r)
+	}
+	if r := b.LessThan(250, nil); r.String() != "[1 4 1099511627776]" {
+		t.Error("incorrect less than", r)
+	}
+	if r := b.GreaterThan(100, nil); r.String() != "[2 3]" {
+		t.Error("incorrect greater than", r)
+	}
+	if r := b.Between(100, 250, sparsebitvector.New(1, 3, 9)); r.String() != "[1 3]" {
+		t.Error("incorrect between", r)
+	}
+	if r := b.LessThan(1<<60, nil); r.Count() != 5 || b.Equal(1<<60, nil).Count() != 0 {
+		t.Error("incorrect comparison with large value", r)
+	}
+	if sum, n := b.Sum(nil); sum != 950 || n != 5 {
+		t.Error("incorrect sum", sum, n)
+	}
+	if min, ok := b.Min(sparsebitvector.New(1, 2, 3)); !ok || min != 100 {
+		t.Error("incorrect min", min)
+	}
+	if max, ok := b.Max(nil); !ok || max != 500 {
+		t.Error("incorrect max", max)
+	}
+	if _, ok := b.Max(sparsebitvector.New(9)); ok {
+		t.Error("unexpected max")
+	}
+	if r := b.TopK(3, nil); r.String() != "[1 2 3]" {
+		t.Error("incorrect top k", r)
+	}
+
+	if !b.Remove(2) || b.Remove(2) || b.Rows().Test(2) {
+		t.Error("incorrect remove")
+	}
+	if max, _ := b.Max(nil); max != 250 {
+		t.Error("incorrect max after remove", max)
+	}
+}
+
+func TestBSIExhaustive(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	b := New()
+	values := map[sparsebitvector.KeyType]uint64{}
+	for i := 0; i < 300; i++ {
+		row := sparsebitvector.KeyType(r.Intn(1000))
+		value := uint64(r.Intn(64))
+		b.Set(row, value)
+		values[row] = value
+	}
+	filter := sparsebitvector.New()
+	for i := 0; i < 500; i++ {
+		filter.Set(sparsebitvector.KeyType(r.Intn(1000)))
+	}
+
+	matching := func(fn func(uint64) bool) *sparsebitvector.SparseBitVector {
+		result := sparsebitvector.New()
+		for row, value := range values {
+			if filter.Test(row) && fn(value) {
+				result.Set(row)
+			}
+		}
+		return result
+	}
+	for c := uint64(0); c < 70; c += 3 {
+		if !b.Equal(c, filter).Equals(matching(func(v uint64) bool { return v == c })) {
+			t.Error("incorrect equal", c)
+		}
+		if !b.LessThan(c, filter).Equals(matching(func(v uint64) bool { return v < c })) {
+			t.Error("incorrect less than", c)
+		}
+		if !b.Between(c, c+20, filter).Equals(matching(func(v uint64) bool { return v >= c && v <= c+20 })) {
+			t.Error("incorrect between", c)
+		}
+	}
+
+	rows := []sparsebitvector.KeyType{}
+	sum, min, max := uint64(0), uint64(1<<63), uint64(0)
+	for row, value := range values {
+		if filter.Test(row) {
+			rows = append(rows, row)
+			sum += value
+			if value < min {
+				min = value
+			}
+			if value > max {
+				max = value
+			}
+		}
+	}
+	if s, n := b.Sum(filter); s != sum || n != len(rows) {
+		t.Error("incorrect sum", s, sum, n)
+	}
+	if m, _ := b.Min(filter); m != min {
+		t.Error("incorrect min", m, min)
+	}
+	if m, _ := b.Max(filter); m != max {
+		t.Error("incorrect max", m, max)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if values[rows[i]] != values[rows[j]] {
+			return values[rows[i]] > values[rows[j]]
+		}
+		return rows[i] < rows[j]
+	})
+	for _, k := range []int{0, 1, 10, 50, len(rows), len(rows) + 5} {
+		expected := sparsebitvector.New()
+		for i := 0; i < k && i < len(rows); i++ {
+			expected.Set(rows[i])
+		}
+		if top := b.TopK(k, filter); !top.Equals(expected) {
+			t.Error("incorrect top k", k, top.Count())
+		}
+	}
+}
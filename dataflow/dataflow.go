@@ -0,0 +1,203 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+// Package dataflow solves monotone dataflow problems over SparseBitVectors.
+package dataflow
+
+import "github.com/neilisaac/sparsebitvector"
+
+// Graph is a directed graph whose nodes are numbered from 0 to Len()-1.
+type Graph interface {
+	Len() int
+	Successors(node int) []int
+	Predecessors(node int) []int
+}
+
+// Direction determines whether facts flow along or against the edges.
+type Direction int
+
+// Directions of a Problem.
+const (
+	Forward Direction = iota
+	Backward
+)
+
+// Meet determines how the facts of converging edges are combined.
+type Meet int
+
+// Meets of a Problem.
+const (
+	Union Meet = iota
+	Intersection
+)
+
+// Problem describes a monotone dataflow problem.
+type Problem struct {
+	Graph     Graph
+	Direction Direction
+	Meet      Meet
+
+	// Gen and Kill define the transfer function gen ∪ (in − kill) of each node.
+	// Nil entries are empty.
+	Gen, Kill []*sparsebitvector.SparseBitVector
+
+	// Transfer, if set, replaces Gen and Kill.
+	// It sets out to the node's transfer function of in, and returns true iff out changed.
+	Transfer func(node int, in, out *sparsebitvector.SparseBitVector) bool
+
+	// Boundary is the fact entering nodes without predecessors,
+	// or leaving nodes without successors for backward problems.
+	// Nil is empty.
+	Boundary *sparsebitvector.SparseBitVector
+
+	// Trace, if set, is called after each node is evaluated.
+	Trace func(node int, in, out *sparsebitvector.SparseBitVector)
+}
+
+// Result holds the fixed point of a Problem.
+// In and Out are indexed by node and refer to the facts at the start and end
+// of each node in program order, regardless of the Problem's Direction.
+type Result struct {
+	In, Out    []*sparsebitvector.SparseBitVector
+	Iterations int // number of node evaluations
+}
+
+// Solve iterates p to a fixed point.
+// Nodes are evaluated from a worklist ordered by reverse postorder in the
+// Problem's Direction. With an Intersection meet, nodes which have not yet
+// been evaluated are ignored when meeting their facts.
+func Solve(p *Problem) *Result {
+	n := p.Graph.Len()
+	result := &Result{
+		In:  make([]*sparsebitvector.SparseBitVector, n),
+		Out: make([]*sparsebitvector.SparseBitVector, n),
+	}
+	for i := 0; i < n; i++ {
+		result.In[i] = sparsebitvector.New()
+		result.Out[i] = sparsebitvector.New()
+	}
+
+	// orient the problem so that facts flow from before to after
+	before, after := result.In, result.Out
+	sources, targets := p.Graph.Predecessors, p.Graph.Successors
+	if p.Direction == Backward {
+		before, after = after, before
+		sources, targets = targets, sources
+	}
+
+	boundary := p.Boundary
+	if boundary == nil {
+		boundary = sparsebitvector.New()
+	}
+	empty := sparsebitvector.New()
+	gen := func(node int) *sparsebitvector.SparseBitVector {
+		if node < len(p.Gen) && p.Gen[node] != nil {
+			return p.Gen[node]
+		}
+		return empty
+	}
+	kill := func(node int) *sparsebitvector.SparseBitVector {
+		if node < len(p.Kill) && p.Kill[node] != nil {
+			return p.Kill[node]
+		}
+		return empty
+	}
+
+	// the worklist holds priorities, and order maps them back to nodes
+	order := postorder(n, sources, targets)
+	for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	priority := make([]int, n)
+	for i, node := range order {
+		priority[node] = i
+	}
+	worklist := sparsebitvector.New()
+	for i := range order {
+		worklist.Set(sparsebitvector.KeyType(i))
+	}
+	evaluated := make([]bool, n)
+
+	for {
+		next, ok := worklist.NextSet(0)
+		if !ok {
+			break
+		}
+		worklist.Unset(next)
+		node := order[next]
+
+		// meet the facts flowing into node
+		in := before[node]
+		if preds := sources(node); len(preds) == 0 {
+			in.SetToUnion(boundary, empty)
+		} else if p.Meet == Union {
+			in.Clear()
+			for _, pred := range preds {
+				in.UnionWith(after[pred])
+			}
+		} else {
+			first := true
+			for _, pred := range preds {
+				if !evaluated[pred] {
+					continue
+				}
+				if first {
+					in.SetToUnion(after[pred], empty)
+					first = false
+				} else {
+					in.IntersectWith(after[pred])
+				}
+			}
+		}
+
+		var changed bool
+		if p.Transfer != nil {
+			changed = p.Transfer(node, in, after[node])
+		} else {
+			changed = after[node].AssignTransfer(in, gen(node), kill(node))
+		}
+		result.Iterations++
+		if p.Trace != nil {
+			p.Trace(node, result.In[node], result.Out[node])
+		}
+
+		if changed || !evaluated[node] {
+			for _, succ := range targets(node) {
+				worklist.Set(sparsebitvector.KeyType(priority[succ]))
+			}
+		}
+		evaluated[node] = true
+	}
+	return result
+}
+
+// postorder returns the nodes in depth first postorder, starting from nodes
+// without sources and then from any nodes not yet reached.
+func postorder(n int, sources, targets func(int) []int) []int {
+	visited := make([]bool, n)
+	order := make([]int, 0, n)
+
+	var visit func(node int)
+	visit = func(node int) {
+		visited[node] = true
+		for _, succ := range targets(node) {
+			if !visited[succ] {
+				visit(succ)
+			}
+		}
+		order = append(order, node)
+	}
+
+	for node := 0; node < n; node++ {
+		if !visited[node] && len(sources(node)) == 0 {
+			visit(node)
+		}
+	}
+	for node := 0; node < n; node++ {
+		if !visited[node] {
+			visit(node)
+		}
+	}
+	return order
+}
@@ -0,0 +1,113 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package dataflow
+
+import (
+	"testing"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// graph lists the successors of each node.
+type graph [][]int
+
+func (g graph) Len() int {
+	return len(g)
+}
+
+func (g graph) Successors(node int) []int {
+	return g[node]
+}
+
+func (g graph) Predecessors(node int) []int {
+	result := []int{}
+	for pred, succs := range g {
+		for _, succ := range succs {
+			if succ == node {
+				result = append(result, pred)
+			}
+		}
+	}
+	return result
+}
+
+// program is the control flow graph of:
+//
+//	0: a = 1
+//	1: b = a + 2
+//	2: c = b + c
+//	3: a = b * 2
+//	4: if a < 9 goto 1
+//	5: return c
+var program = graph{{1}, {2}, {3}, {4}, {1, 5}, {}}
+
+const (
+	a = iota
+	b
+	c
+)
+
+func vectors(sets ...[]sparsebitvector.KeyType) []*sparsebitvector.SparseBitVector {
+	result := []*sparsebitvector.SparseBitVector{}
+	for _, set := range sets {
+		result = append(result, sparsebitvector.New(set...))
+	}
+	return result
+}
+
+func check(t *testing.T, name string, actual []*sparsebitvector.SparseBitVector, expected []string) {
+	for node, vec := range actual {
+		if vec.String() != expected[node] {
+			t.Error("incorrect", name, "of node", node, vec, "expected", expected[node])
+		}
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	use := vectors(nil, []sparsebitvector.KeyType{a}, []sparsebitvector.KeyType{b, c}, []sparsebitvector.KeyType{b}, []sparsebitvector.KeyType{a}, []sparsebitvector.KeyType{c})
+	def := vectors([]sparsebitvector.KeyType{a}, []sparsebitvector.KeyType{b}, []sparsebitvector.KeyType{c}, []sparsebitvector.KeyType{a}, nil, nil)
+
+	result := Solve(&Problem{Graph: program, Direction: Backward, Meet: Union, Gen: use, Kill: def})
+	check(t, "live in", result.In, []string{"[2]", "[0 2]", "[1 2]", "[1 2]", "[0 2]", "[2]"})
+	check(t, "live out", result.Out, []string{"[0 2]", "[1 2]", "[1 2]", "[0 2]", "[0 2]", "[]"})
+	if result.Iterations < program.Len() {
+		t.Error("too few iterations", result.Iterations)
+	}
+}
+
+func TestReachingDefinitions(t *testing.T) {
+	// definition i is made by node i
+	gen := vectors([]sparsebitvector.KeyType{0}, []sparsebitvector.KeyType{1}, []sparsebitvector.KeyType{2}, []sparsebitvector.KeyType{3})
+	kill := vectors([]sparsebitvector.KeyType{3}, nil, nil, []sparsebitvector.KeyType{0})
+
+	result := Solve(&Problem{Graph: program, Direction: Forward, Meet: Union, Gen: gen, Kill: kill})
+	check(t, "reaching in", result.In, []string{"[]", "[0 1 2 3]", "[0 1 2 3]", "[0 1 2 3]", "[1 2 3]", "[1 2 3]"})
+	check(t, "reaching out", result.Out, []string{"[0]", "[0 1 2 3]", "[0 1 2 3]", "[1 2 3]", "[1 2 3]", "[1 2 3]"})
+}
+
+func TestIntersectionWithTransfer(t *testing.T) {
+	// diamond: 0 -> {1, 2} -> 3
+	diamond := graph{{1, 2}, {3}, {3}, {}}
+	gen := vectors([]sparsebitvector.KeyType{10}, []sparsebitvector.KeyType{20}, []sparsebitvector.KeyType{20, 30}, nil)
+
+	traced := 0
+	result := Solve(&Problem{
+		Graph:     diamond,
+		Direction: Forward,
+		Meet:      Intersection,
+		Boundary:  sparsebitvector.New(1),
+		Transfer: func(node int, in, out *sparsebitvector.SparseBitVector) bool {
+			return out.SetToUnion(in, gen[node])
+		},
+		Trace: func(node int, in, out *sparsebitvector.SparseBitVector) {
+			traced++
+		},
+	})
+	check(t, "available in", result.In, []string{"[1]", "[1 10]", "[1 10]", "[1 10 20]"})
+	check(t, "available out", result.Out, []string{"[1 10]", "[1 10 20]", "[1 10 20 30]", "[1 10 20]"})
+	if traced != result.Iterations || traced != diamond.Len() {
+		t.Error("unexpected iterations", traced, result.Iterations)
+	}
+}
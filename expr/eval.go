@@ -0,0 +1,143 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// DefaultMaxRange is the MaxRange of a parsed Expr.
+const DefaultMaxRange = 1 << 24
+
+// Plan describes how an expression was evaluated.
+// Operands are listed in the order they were combined, and operands skipped
+// because an intersection or difference was already empty are omitted.
+type Plan struct {
+	Operation string // name, range or operation
+	Count     int    // true bits of the result
+	Cost      int    // true bits of the operands combined, a measure of merge work
+	Operands  []*Plan
+}
+
+func (p *Plan) String() string {
+	b := strings.Builder{}
+	p.format(&b, 0)
+	return b.String()
+}
+
+func (p *Plan) format(b *strings.Builder, depth int) {
+	fmt.Fprintf(b, "%s%s: %d", strings.Repeat("  ", depth), p.Operation, p.Count)
+	if p.Operands != nil {
+		fmt.Fprintf(b, " (cost %d)", p.Cost)
+	}
+	b.WriteString("\n")
+	for _, operand := range p.Operands {
+		operand.format(b, depth+1)
+	}
+}
+
+// operand is an evaluated subexpression.
+// Named vectors are borrowed, and only copied when they must be modified.
+type operand struct {
+	vec   *sparsebitvector.SparseBitVector
+	owned bool
+	plan  *Plan
+}
+
+// own returns a vector holding o which may be modified.
+func (o operand) own() *sparsebitvector.SparseBitVector {
+	if o.owned {
+		return o.vec
+	}
+	return sparsebitvector.UnionAll(o.vec)
+}
+
+// Eval evaluates e over the named vectors, which are not modified.
+// Operands are evaluated first, then combined in place in an order chosen by
+// their Count: intersections start from the smallest operand and stop once empty,
+// unions start from the largest, and differences subtract the largest first.
+// It returns a new vector along with the plan that computed it.
+func (e *Expr) Eval(vars map[string]*sparsebitvector.SparseBitVector) (*sparsebitvector.SparseBitVector, *Plan, error) {
+	result, err := e.eval(e.root, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.own(), result.plan, nil
+}
+
+// Eval parses and evaluates input over the named vectors.
+func Eval(input string, vars map[string]*sparsebitvector.SparseBitVector) (*sparsebitvector.SparseBitVector, *Plan, error) {
+	e, err := Parse(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e.Eval(vars)
+}
+
+func (e *Expr) eval(n *node, vars map[string]*sparsebitvector.SparseBitVector) (operand, error) {
+	switch n.op {
+	case nameOp:
+		vec, ok := vars[n.name]
+		if !ok {
+			return operand{}, fmt.Errorf("expr: unknown name %q", n.name)
+		}
+		return operand{vec, false, &Plan{Operation: n.name, Count: vec.Count()}}, nil
+	case rangeOp:
+		if n.hi-n.lo >= e.MaxRange {
+			return operand{}, fmt.Errorf("expr: range [%d-%d] exceeds %d keys", n.lo, n.hi, e.MaxRange)
+		}
+		vec := sparsebitvector.NewRange(n.lo, n.hi)
+		return operand{vec, true, &Plan{Operation: fmt.Sprintf("[%d-%d]", n.lo, n.hi), Count: vec.Count()}}, nil
+	}
+
+	operands := []operand{}
+	for _, child := range n.children {
+		o, err := e.eval(child, vars)
+		if err != nil {
+			return operand{}, err
+		}
+		operands = append(operands, o)
+	}
+
+	// the first operand of a difference stays first
+	rest := operands
+	if n.op == differenceOp {
+		rest = operands[1:]
+	}
+	sort.SliceStable(rest, func(i, j int) bool {
+		if n.op == intersectionOp {
+			return rest[i].vec.Count() < rest[j].vec.Count()
+		}
+		return rest[i].vec.Count() > rest[j].vec.Count()
+	})
+
+	plan := &Plan{Operation: opNames[n.op]}
+	result := operands[0].own()
+	plan.Operands = append(plan.Operands, operands[0].plan)
+	plan.Cost = operands[0].vec.Count()
+	for _, o := range operands[1:] {
+		if result.Count() == 0 && n.op != unionOp && n.op != symmetricDifferenceOp {
+			break
+		}
+		plan.Operands = append(plan.Operands, o.plan)
+		plan.Cost += o.vec.Count()
+		switch n.op {
+		case unionOp:
+			result.UnionWith(o.vec)
+		case intersectionOp:
+			result.IntersectWith(o.vec)
+		case differenceOp:
+			result.IntersectWithComplement(o.vec)
+		case symmetricDifferenceOp:
+			result.SymmetricDifferenceWith(o.vec)
+		}
+	}
+	plan.Count = result.Count()
+	return operand{result, true, plan}, nil
+}
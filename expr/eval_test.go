@@ -0,0 +1,103 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package expr
+
+import (
+	"testing"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+func vars() map[string]*sparsebitvector.SparseBitVector {
+	return map[string]*sparsebitvector.SparseBitVector{
+		"active":  sparsebitvector.New(1, 2, 3, 4, 5, 6, 7, 8),
+		"premium": sparsebitvector.New(2, 4, 6, 8, 100),
+		"churned": sparsebitvector.New(4),
+		"beta":    sparsebitvector.New(7, 200),
+		"empty":   sparsebitvector.New(),
+	}
+}
+
+func TestEval(t *testing.T) {
+	v := vars()
+	for input, expected := range map[string]string{
+		"(active & premium) - churned | beta": "[2 6 7 8 200]",
+		"active ^ premium ^ beta":             "[1 3 5 100 200]",
+		"active - premium - churned":          "[1 3 5 7]",
+		"active - (premium - churned)":        "[1 3 4 5 7]",
+		"premium & [3-99]":                    "[4 6 8]",
+		"empty & active - beta":               "[]",
+		"premium | [7]":                       "[2 4 6 7 8 100]",
+		"churned":                             "[4]",
+	} {
+		result, _, err := Eval(input, v)
+		if err != nil {
+			t.Error("unexpected error", input, err)
+		} else if result.String() != expected {
+			t.Error("incorrect result", input, result)
+		}
+	}
+
+	// operands are not modified, and the result is not shared
+	result, _, _ := Eval("churned", v)
+	result.Set(5)
+	if v["churned"].String() != "[4]" || v["active"].Count() != 8 || v["premium"].Count() != 5 {
+		t.Error("operand modified", v)
+	}
+
+	if _, _, err := Eval("active & missing", v); err == nil {
+		t.Error("expected error for unknown name")
+	}
+	if _, _, err := Eval("active & [0-99999999999]", v); err == nil {
+		t.Error("expected error for oversized range")
+	}
+	if result, _, err := Eval("[0-16777215]", v); err != nil || result.Count() != 1<<24 {
+		t.Error("incorrect largest range", err)
+	}
+
+	e, err := Parse("active | [0-99]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.MaxRange = 100
+	if result, _, err := e.Eval(v); err != nil || result.Count() != 100 {
+		t.Error("incorrect range within MaxRange", err)
+	}
+	e.MaxRange = 99
+	if _, _, err := e.Eval(v); err == nil {
+		t.Error("expected error for range beyond MaxRange")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	_, plan, err := Eval("(active & churned & premium) - beta | empty | premium", vars())
+	if err != nil {
+		t.Error("unexpected error", err)
+	}
+	expected := `union: 5 (cost 6)
+  premium: 5
+  difference: 1 (cost 3)
+    intersection: 1 (cost 14)
+      churned: 1
+      premium: 5
+      active: 8
+    beta: 2
+  empty: 0
+`
+	if plan.String() != expected {
+		t.Error("incorrect plan", plan)
+	}
+
+	// the intersection is empty before active is combined
+	_, plan, _ = Eval("(active & churned & beta) - premium", vars())
+	expected = `difference: 0 (cost 0)
+  intersection: 0 (cost 3)
+    churned: 1
+    beta: 2
+`
+	if plan.String() != expected {
+		t.Error("incorrect plan", plan)
+	}
+}
@@ -0,0 +1,198 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+// Package expr parses and evaluates set expressions over named SparseBitVectors.
+//
+// Expressions combine names, ranges such as [10-20] or [5], and parenthesized
+// expressions with the operators below, from lowest to highest precedence:
+//
+//	|  union
+//	^  symmetric difference
+//	&  intersection
+//	-  difference
+//
+// Names consist of letters, digits, underscores and dots, and start with a letter or underscore.
+package expr
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+type op int
+
+const (
+	nameOp op = iota
+	rangeOp
+	unionOp
+	symmetricDifferenceOp
+	intersectionOp
+	differenceOp
+)
+
+var opNames = map[op]string{
+	unionOp:               "union",
+	symmetricDifferenceOp: "symmetric difference",
+	intersectionOp:        "intersection",
+	differenceOp:          "difference",
+}
+
+// operators maps each operator character to its operation, in ascending precedence.
+var operators = []struct {
+	char byte
+	op   op
+}{{'|', unionOp}, {'^', symmetricDifferenceOp}, {'&', intersectionOp}, {'-', differenceOp}}
+
+// node is an operand or an n-ary operation in a parsed expression.
+type node struct {
+	op       op
+	name     string
+	lo, hi   sparsebitvector.KeyType
+	children []*node
+}
+
+// Expr is a parsed set expression.
+type Expr struct {
+	// MaxRange is the largest number of keys a range may hold.
+	// Ranges are evaluated into vectors, so larger ranges are rejected with an error.
+	MaxRange sparsebitvector.KeyType
+
+	root *node
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+// Parse parses a set expression.
+func Parse(input string) (*Expr, error) {
+	p := &parser{input: input}
+	root, err := p.parse(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.pos != len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+	return &Expr{MaxRange: DefaultMaxRange, root: root}, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("expr: "+format+" at offset %d", append(args, p.pos)...)
+}
+
+// skip advances past whitespace.
+func (p *parser) skip() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+// peek returns the next character, or 0 at the end of the input.
+func (p *parser) peek() byte {
+	if p.skip(); p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// parse parses operations of the given precedence level and above.
+// Chains of the same operation are flattened into a single node, and so are
+// nested unions, intersections and symmetric differences, which are associative.
+func (p *parser) parse(level int) (*node, error) {
+	if level == len(operators) {
+		return p.primary()
+	}
+	left, err := p.parse(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == operators[level].char {
+		p.pos++
+		right, err := p.parse(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		operation := operators[level].op
+		if left.op != operation || left.children == nil {
+			left = &node{op: operation, children: []*node{left}}
+		}
+		if right.op == operation && operation != differenceOp {
+			left.children = append(left.children, right.children...)
+		} else {
+			left.children = append(left.children, right)
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) primary() (*node, error) {
+	switch c := p.peek(); {
+	case c == '(':
+		p.pos++
+		n, err := p.parse(0)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("expected ')'")
+		}
+		p.pos++
+		return n, nil
+	case c == '[':
+		p.pos++
+		lo, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		hi := lo
+		if p.peek() == '-' {
+			p.pos++
+			if hi, err = p.number(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek() != ']' {
+			return nil, p.errorf("expected ']'")
+		}
+		p.pos++
+		if lo > hi {
+			return nil, p.errorf("empty range [%d-%d]", lo, hi)
+		}
+		return &node{op: rangeOp, lo: lo, hi: hi}, nil
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		start := p.pos
+		for p.pos < len(p.input) && isNameChar(p.input[p.pos]) {
+			p.pos++
+		}
+		return &node{op: nameOp, name: p.input[start:p.pos]}, nil
+	case c == 0:
+		return nil, p.errorf("unexpected end of expression")
+	default:
+		return nil, p.errorf("unexpected %q", c)
+	}
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *parser) number() (sparsebitvector.KeyType, error) {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, p.errorf("expected number")
+	}
+	value, err := strconv.ParseUint(p.input[start:p.pos], 10, 64)
+	if err != nil {
+		return 0, p.errorf("invalid number %q", p.input[start:p.pos])
+	}
+	return sparsebitvector.KeyType(value), nil
+}
@@ -0,0 +1,55 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package expr
+
+import (
+	"fmt"
+	"testing"
+)
+
+// format returns the structure of n with explicit parentheses.
+func format(n *node) string {
+	switch n.op {
+	case nameOp:
+		return n.name
+	case rangeOp:
+		return fmt.Sprintf("[%d-%d]", n.lo, n.hi)
+	}
+	result := "("
+	for i, child := range n.children {
+		if i > 0 {
+			result += " " + string(operators[n.op-unionOp].char) + " "
+		}
+		result += format(child)
+	}
+	return result + ")"
+}
+
+func TestParse(t *testing.T) {
+	for input, expected := range map[string]string{
+		"a":                                   "a",
+		"(active & premium) - churned | beta": "(((active & premium) - churned) | beta)",
+		"a | b & c ^ d":                       "(a | ((b & c) ^ d))",
+		"a - b - c & d":                       "((a - b - c) & d)",
+		"(a - b) - c":                         "(a - b - c)",
+		"a - (b - c)":                         "(a - (b - c))",
+		"(a | b) | (c | d)":                   "(a | b | c | d)",
+		"[1-5] & x_1.y":                       "([1-5] & x_1.y)",
+		" [ 3 ] ":                             "[3-3]",
+	} {
+		e, err := Parse(input)
+		if err != nil {
+			t.Error("unexpected error", input, err)
+		} else if f := format(e.root); f != expected {
+			t.Error("incorrect parse", input, f)
+		}
+	}
+
+	for _, input := range []string{"", "a |", "(a", "a)", "[1-", "[5-1]", "[a]", "1a", "a $ b", "[99999999999999999999]"} {
+		if _, err := Parse(input); err == nil {
+			t.Error("expected error", input)
+		}
+	}
+}
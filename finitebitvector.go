@@ -4,7 +4,10 @@
 
 package sparsebitvector
 
-import "fmt"
+import (
+	"fmt"
+	"math/bits"
+)
 
 type elementwordtype uint64
 
@@ -102,13 +105,29 @@ func (vec *FiniteBitVector) FindNext(index int) int {
 	return -1
 }
 
+// FindPrev returns the last true bit at or before index, or -1 if none exist.
+func (vec *FiniteBitVector) FindPrev(index int) int {
+	if index < 0 {
+		return -1
+	}
+	if index >= ElementSize {
+		index = ElementSize - 1
+	}
+	word, bit := vec.getWordBit(uint(index))
+	shift := bitsperword - 1 - bit
+	for w := int(word); w >= 0; w-- {
+		if value := uint64(vec[w]) << shift; value != 0 {
+			return w*bitsperword + bitsperword - 1 - int(shift) - bits.LeadingZeros64(value)
+		}
+		shift = 0
+	}
+	return -1
+}
+
 // Count returns the number of true bits within the ELement.
 func (vec *FiniteBitVector) Count() (count int) {
 	for _, word := range vec {
-		for word != 0 {
-			count += int(word & 1)
-			word >>= 1
-		}
+		count += bits.OnesCount64(uint64(word))
 	}
 	return
 }
@@ -117,11 +136,7 @@ func (vec *FiniteBitVector) Count() (count int) {
 func (vec *FiniteBitVector) IntersectionSize(vec2 *FiniteBitVector) int {
 	intersection := 0
 	for w := 0; w < wordsperelement; w++ {
-		value := vec[w] & vec2[w]
-		for value != 0 {
-			value &= value - 1
-			intersection++
-		}
+		intersection += bits.OnesCount64(uint64(vec[w] & vec2[w]))
 	}
 	return intersection
 }
@@ -131,14 +146,8 @@ func (vec *FiniteBitVector) UnionAndIntersectionSize(vec2 *FiniteBitVector) (int
 	union := 0
 	intersection := 0
 	for w := 0; w < wordsperelement; w++ {
-		word1 := vec[w]
-		word2 := vec2[w]
-		for word1 != 0 || word2 != 0 {
-			union += int(word1&1 | word2&1)
-			intersection += int(word1 & word2 & 1)
-			word1 >>= 1
-			word2 >>= 1
-		}
+		union += bits.OnesCount64(uint64(vec[w] | vec2[w]))
+		intersection += bits.OnesCount64(uint64(vec[w] & vec2[w]))
 	}
 	return union, intersection
 }
@@ -141,6 +141,38 @@ func TestFindNext(t *testing.T) {
 	}
 }
 
+func TestFindPrev(t *testing.T) {
+	vec := NewFiniteBitVector()
+
+	if i := vec.FindPrev(ElementSize - 1); i != -1 {
+		t.Error("unexpected result", i, vec)
+	}
+
+	vec.Set(0)
+	vec.Set(5)
+	vec.Set(63)
+	vec.Set(64)
+	vec.Set(127)
+	if i := vec.FindPrev(ElementSize - 1); i != 127 {
+		t.Error("unexpected result", i, vec)
+	}
+	if i := vec.FindPrev(126); i != 64 {
+		t.Error("unexpected result", i, vec)
+	}
+	if i := vec.FindPrev(63); i != 63 {
+		t.Error("unexpected result", i, vec)
+	}
+	if i := vec.FindPrev(62); i != 5 {
+		t.Error("unexpected result", i, vec)
+	}
+	if i := vec.FindPrev(4); i != 0 {
+		t.Error("unexpected result", i, vec)
+	}
+	if i := vec.FindPrev(-1); i != -1 {
+		t.Error("unexpected result", i, vec)
+	}
+}
+
 func TestFiniteBitVectorBinaryOperations(t *testing.T) {
 	vec1 := NewFiniteBitVector()
 	vec2 := NewFiniteBitVector()
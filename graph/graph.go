@@ -0,0 +1,263 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+// Package graph provides graph algorithms over adjacency sets stored as SparseBitVectors.
+// Traversals work a frontier at a time using bulk set operations.
+package graph
+
+import "github.com/neilisaac/sparsebitvector"
+
+// Graph is a directed graph with one successor and one predecessor
+// SparseBitVector per node.
+type Graph struct {
+	nodes *sparsebitvector.SparseBitVector
+	succs map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector
+	preds map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector
+	edges int
+}
+
+// Mode selects how BFS expands its frontier.
+type Mode int
+
+// Modes of BFS.
+const (
+	// Auto switches between TopDown and BottomUp depending on the frontier size.
+	Auto Mode = iota
+	// TopDown unions the successors of the frontier.
+	TopDown
+	// BottomUp adds each unvisited node with a predecessor in the frontier.
+	BottomUp
+)
+
+// Thresholds for switching modes, from Beamer et al.'s direction-optimizing BFS.
+const (
+	alpha = 14
+	beta  = 24
+)
+
+// New creates an empty Graph.
+func New() *Graph {
+	return &Graph{
+		nodes: sparsebitvector.New(),
+		succs: make(map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector),
+		preds: make(map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector),
+	}
+}
+
+// AddNode adds a node without any edges.
+func (g *Graph) AddNode(node sparsebitvector.KeyType) {
+	g.nodes.Set(node)
+}
+
+// AddEdge adds an edge from one node to another, and returns true iff it was new.
+func (g *Graph) AddEdge(from, to sparsebitvector.KeyType) bool {
+	g.nodes.Set(from)
+	g.nodes.Set(to)
+	if !adjacency(g.succs, from).Set(to) {
+		return false
+	}
+	adjacency(g.preds, to).Set(from)
+	g.edges++
+	return true
+}
+
+func adjacency(m map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector, node sparsebitvector.KeyType) *sparsebitvector.SparseBitVector {
+	vec, ok := m[node]
+	if !ok {
+		vec = sparsebitvector.New()
+		m[node] = vec
+	}
+	return vec
+}
+
+// degree returns the number of nodes adjacent to node.
+func degree(m map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector, node sparsebitvector.KeyType) int {
+	if vec, ok := m[node]; ok {
+		return vec.Count()
+	}
+	return 0
+}
+
+// degrees returns the sum of the degrees in m of nodes.
+func degrees(m map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector, nodes *sparsebitvector.SparseBitVector) int {
+	sum := 0
+	for node, ok := nodes.NextSet(0); ok; node, ok = nodes.NextSet(node + 1) {
+		sum += degree(m, node)
+	}
+	return sum
+}
+
+// adjacent returns the union of the vectors in m of nodes.
+func adjacent(m map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector, nodes *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	vecs := []*sparsebitvector.SparseBitVector{}
+	for node, ok := nodes.NextSet(0); ok; node, ok = nodes.NextSet(node + 1) {
+		if vec, ok := m[node]; ok {
+			vecs = append(vecs, vec)
+		}
+	}
+	return sparsebitvector.UnionAll(vecs...)
+}
+
+// meets returns true iff vec and set have a key in common,
+// stopping at the first one found.
+func meets(vec, set *sparsebitvector.SparseBitVector) bool {
+	for key, ok := vec.NextSet(0); ok; key, ok = vec.NextSet(key + 1) {
+		if set.Test(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Nodes returns the set of nodes, which must not be modified.
+func (g *Graph) Nodes() *sparsebitvector.SparseBitVector {
+	return g.nodes
+}
+
+// Successors returns the successors of node, which must not be modified
+// unless node has none, in which case a new empty vector is returned.
+func (g *Graph) Successors(node sparsebitvector.KeyType) *sparsebitvector.SparseBitVector {
+	if vec, ok := g.succs[node]; ok {
+		return vec
+	}
+	return sparsebitvector.New()
+}
+
+// Predecessors returns the predecessors of node, which must not be modified
+// unless node has none, in which case a new empty vector is returned.
+func (g *Graph) Predecessors(node sparsebitvector.KeyType) *sparsebitvector.SparseBitVector {
+	if vec, ok := g.preds[node]; ok {
+		return vec
+	}
+	return sparsebitvector.New()
+}
+
+// Edges returns the number of edges.
+func (g *Graph) Edges() int {
+	return g.edges
+}
+
+// BFS returns the frontiers of a breadth first search from sources:
+// the sources themselves, then the nodes first reached at each following depth.
+func (g *Graph) BFS(sources *sparsebitvector.SparseBitVector, mode Mode) []*sparsebitvector.SparseBitVector {
+	return g.bfs(sources, nil, mode, g.succs, g.preds)
+}
+
+// Reachable returns the nodes reachable from sources, including the sources.
+func (g *Graph) Reachable(sources *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	return sparsebitvector.UnionAll(g.bfs(sources, nil, Auto, g.succs, g.preds)...)
+}
+
+// ReverseReachable returns the nodes from which targets are reachable, including the targets.
+func (g *Graph) ReverseReachable(targets *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	return sparsebitvector.UnionAll(g.bfs(targets, nil, Auto, g.preds, g.succs)...)
+}
+
+// ReachableExcept returns the nodes reachable from a but not from b.
+func (g *Graph) ReachableExcept(a, b *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	result := g.Reachable(a)
+	result.IntersectWithComplement(g.Reachable(b))
+	return result
+}
+
+// bfs searches along out and against in, visiting only nodes within the
+// given set unless it is nil.
+func (g *Graph) bfs(sources, within *sparsebitvector.SparseBitVector, mode Mode, out, in map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector) []*sparsebitvector.SparseBitVector {
+	frontier := sparsebitvector.New()
+	frontier.UnionWith(sources)
+	if within != nil {
+		frontier.IntersectWith(within)
+	}
+	visited := sparsebitvector.New()
+	visited.UnionWith(frontier)
+
+	// edges left to check from unvisited nodes, for choosing a mode
+	unexplored := g.edges - degrees(in, frontier)
+
+	bottomUp := mode == BottomUp
+	levels := []*sparsebitvector.SparseBitVector{}
+	for frontier.Count() != 0 {
+		levels = append(levels, frontier)
+
+		if mode == Auto {
+			frontierEdges := degrees(out, frontier)
+			if !bottomUp && frontierEdges > unexplored/alpha {
+				bottomUp = true
+			} else if bottomUp && frontier.Count() < g.nodes.Count()/beta {
+				bottomUp = false
+			}
+		}
+
+		next := sparsebitvector.New()
+		if bottomUp {
+			candidates := sparsebitvector.New()
+			candidates.SetToDifference(g.nodes, visited)
+			if within != nil {
+				candidates.IntersectWith(within)
+			}
+			for node, ok := candidates.NextSet(0); ok; node, ok = candidates.NextSet(node + 1) {
+				if vec, ok := in[node]; ok && meets(vec, frontier) {
+					next.Set(node)
+				}
+			}
+		} else {
+			next = adjacent(out, frontier)
+			next.IntersectWithComplement(visited)
+			if within != nil {
+				next.IntersectWith(within)
+			}
+		}
+
+		visited.UnionWith(next)
+		unexplored -= degrees(in, next)
+		frontier = next
+	}
+	return levels
+}
+
+// StronglyConnectedComponents returns the strongly connected components of g
+// using the forward-backward algorithm: the nodes both reachable from and
+// reaching a pivot form its component, and the remaining nodes split into
+// three independent subproblems.
+func (g *Graph) StronglyConnectedComponents() []*sparsebitvector.SparseBitVector {
+	components := []*sparsebitvector.SparseBitVector{}
+	work := []*sparsebitvector.SparseBitVector{sparsebitvector.UnionAll(g.nodes)}
+	for len(work) > 0 {
+		within := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		// trim nodes without predecessors or successors in the subproblem,
+		// keeping those which are both a predecessor and a successor of some node in it
+		kept := adjacent(g.preds, within)
+		kept.IntersectWith(adjacent(g.succs, within))
+		trimmed := sparsebitvector.New()
+		trimmed.SetToDifference(within, kept)
+		for node, ok := trimmed.NextSet(0); ok; node, ok = trimmed.NextSet(node + 1) {
+			components = append(components, sparsebitvector.New(node))
+		}
+		within.IntersectWithComplement(trimmed)
+		pivot, ok := within.NextSet(0)
+		if !ok {
+			continue
+		}
+
+		source := sparsebitvector.New(pivot)
+		forward := sparsebitvector.UnionAll(g.bfs(source, within, TopDown, g.succs, g.preds)...)
+		backward := sparsebitvector.UnionAll(g.bfs(source, within, TopDown, g.preds, g.succs)...)
+		component := sparsebitvector.IntersectAll(forward, backward)
+		components = append(components, component)
+
+		forward.IntersectWithComplement(component)
+		backward.IntersectWithComplement(component)
+		within.IntersectWithComplement(forward)
+		within.IntersectWithComplement(backward)
+		within.IntersectWithComplement(component)
+		for _, subproblem := range []*sparsebitvector.SparseBitVector{forward, backward, within} {
+			if subproblem.Count() != 0 {
+				work = append(work, subproblem)
+			}
+		}
+	}
+	return components
+}
@@ -0,0 +1,117 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package graph
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+func build(edges ...[2]sparsebitvector.KeyType) *Graph {
+	g := New()
+	for _, edge := range edges {
+		g.AddEdge(edge[0], edge[1])
+	}
+	return g
+}
+
+func TestGraph(t *testing.T) {
+	g := build([2]sparsebitvector.KeyType{1, 2}, [2]sparsebitvector.KeyType{1, 3}, [2]sparsebitvector.KeyType{2, 3})
+	if g.AddEdge(1, 2) || g.Edges() != 3 {
+		t.Error("unexpected edge", g.Edges())
+	}
+	g.AddNode(10)
+	if g.Nodes().String() != "[1 2 3 10]" {
+		t.Error("incorrect nodes", g.Nodes())
+	}
+	if g.Successors(1).String() != "[2 3]" || g.Predecessors(3).String() != "[1 2]" || g.Successors(10).Count() != 0 {
+		t.Error("incorrect adjacency")
+	}
+
+	// the empty adjacency of one graph is not shared with another
+	g.Successors(20).Set(1)
+	if New().Successors(20).Count() != 0 || g.Predecessors(20).Count() != 0 {
+		t.Error("empty adjacency was shared")
+	}
+}
+
+func TestBFS(t *testing.T) {
+	// a binary tree of depth 10 plus a back edge and an unreachable node
+	g := New()
+	for node := sparsebitvector.KeyType(1); node < 1024; node++ {
+		g.AddEdge(node, 2*node)
+		g.AddEdge(node, 2*node+1)
+	}
+	g.AddEdge(1000, 1)
+	g.AddNode(1 << 40)
+
+	for _, mode := range []Mode{Auto, TopDown, BottomUp} {
+		levels := g.BFS(sparsebitvector.New(1), mode)
+		if len(levels) != 11 {
+			t.Error("incorrect depth", mode, len(levels))
+			continue
+		}
+		for depth, level := range levels {
+			if level.Count() != 1<<uint(depth) {
+				t.Error("incorrect level size", mode, depth, level.Count())
+			}
+			if first, _ := level.NextSet(0); first != 1<<uint(depth) {
+				t.Error("incorrect level", mode, depth, first)
+			}
+		}
+	}
+
+	if levels := g.BFS(sparsebitvector.New(1<<40), Auto); len(levels) != 1 {
+		t.Error("incorrect depth", len(levels))
+	}
+}
+
+func TestReachability(t *testing.T) {
+	g := build(
+		[2]sparsebitvector.KeyType{1, 2}, [2]sparsebitvector.KeyType{2, 3},
+		[2]sparsebitvector.KeyType{3, 1}, [2]sparsebitvector.KeyType{3, 4},
+		[2]sparsebitvector.KeyType{5, 4}, [2]sparsebitvector.KeyType{4, 6},
+	)
+
+	if r := g.Reachable(sparsebitvector.New(1)); r.String() != "[1 2 3 4 6]" {
+		t.Error("incorrect reachable", r)
+	}
+	if r := g.Reachable(sparsebitvector.New(5, 6)); r.String() != "[4 5 6]" {
+		t.Error("incorrect reachable", r)
+	}
+	if r := g.ReverseReachable(sparsebitvector.New(4)); r.String() != "[1 2 3 4 5]" {
+		t.Error("incorrect reverse reachable", r)
+	}
+	if r := g.ReachableExcept(sparsebitvector.New(1), sparsebitvector.New(5)); r.String() != "[1 2 3]" {
+		t.Error("incorrect reachable difference", r)
+	}
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	g := build(
+		[2]sparsebitvector.KeyType{1, 2}, [2]sparsebitvector.KeyType{2, 3}, [2]sparsebitvector.KeyType{3, 1},
+		[2]sparsebitvector.KeyType{3, 4}, [2]sparsebitvector.KeyType{4, 5}, [2]sparsebitvector.KeyType{5, 4},
+		[2]sparsebitvector.KeyType{5, 6}, [2]sparsebitvector.KeyType{7, 7}, [2]sparsebitvector.KeyType{6, 8},
+	)
+
+	components := []string{}
+	total := 0
+	for _, component := range g.StronglyConnectedComponents() {
+		components = append(components, component.String())
+		total += component.Count()
+	}
+	sort.Strings(components)
+	expected := []string{"[1 2 3]", "[4 5]", "[6]", "[7]", "[8]"}
+	if total != g.Nodes().Count() || len(components) != len(expected) {
+		t.Error("incorrect components", components)
+	}
+	for i := range expected {
+		if i < len(components) && components[i] != expected[i] {
+			t.Error("incorrect components", components)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+// Package index provides an inverted index from terms to SparseBitVectors of document IDs.
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// Index maps terms to posting vectors of the documents containing them.
+type Index struct {
+	postings map[string]*sparsebitvector.SparseBitVector
+	terms    map[sparsebitvector.KeyType][]string // of each document
+	docs     *sparsebitvector.SparseBitVector
+}
+
+// New creates an empty Index.
+func New() *Index {
+	return &Index{
+		postings: make(map[string]*sparsebitvector.SparseBitVector),
+		terms:    make(map[sparsebitvector.KeyType][]string),
+		docs:     sparsebitvector.New(),
+	}
+}
+
+// Add adds terms to the document doc, adding the document if necessary.
+func (ix *Index) Add(doc sparsebitvector.KeyType, terms ...string) {
+	ix.docs.Set(doc)
+	if _, ok := ix.terms[doc]; !ok {
+		ix.terms[doc] = []string{}
+	}
+	for _, term := range terms {
+		posting, ok := ix.postings[term]
+		if !ok {
+			// the summary lets intersections skip ahead over sparse postings
+			posting = sparsebitvector.New()
+			posting.EnableSummary()
+			ix.postings[term] = posting
+		}
+		if posting.Set(doc) {
+			ix.terms[doc] = append(ix.terms[doc], term)
+		}
+	}
+}
+
+// Remove removes the document doc and its terms.
+// It returns true iff ix changed.
+func (ix *Index) Remove(doc sparsebitvector.KeyType) bool {
+	terms, ok := ix.terms[doc]
+	if !ok {
+		return false
+	}
+	for _, term := range terms {
+		if ix.postings[term].Unset(doc); ix.postings[term].Count() == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.terms, doc)
+	ix.docs.Unset(doc)
+	return true
+}
+
+// Postings returns the documents containing term.
+// The result is shared with ix and must not be modified.
+func (ix *Index) Postings(term string) *sparsebitvector.SparseBitVector {
+	if posting, ok := ix.postings[term]; ok {
+		return posting
+	}
+	return sparsebitvector.New()
+}
+
+// Docs returns the documents in ix.
+// The result is shared with ix and must not be modified.
+func (ix *Index) Docs() *sparsebitvector.SparseBitVector {
+	return ix.docs
+}
+
+// Terms returns the number of distinct terms in ix.
+func (ix *Index) Terms() int {
+	return len(ix.postings)
+}
+
+// WriteTo writes ix to w as the documents' SparseBitVector, a little-endian
+// uint64 term count, and each term's length, bytes and posting vector,
+// with the vectors in their binary block format.
+func (ix *Index) WriteTo(w io.Writer) (int64, error) {
+	terms := []string{}
+	for term := range ix.postings {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	written, err := ix.docs.WriteTo(w)
+	buf := make([]byte, 8)
+	write := func(data []byte) {
+		if err == nil {
+			var n int
+			n, err = w.Write(data)
+			written += int64(n)
+		}
+	}
+	binary.LittleEndian.PutUint64(buf, uint64(len(terms)))
+	write(buf)
+	for _, term := range terms {
+		binary.LittleEndian.PutUint64(buf, uint64(len(term)))
+		write(buf)
+		write([]byte(term))
+		if err == nil {
+			var n int64
+			n, err = ix.postings[term].WriteTo(w)
+			written += n
+		}
+	}
+	return written, err
+}
+
+// ReadFrom replaces the contents of ix with an index read from r in the format written by WriteTo.
+func (ix *Index) ReadFrom(r io.Reader) (int64, error) {
+	*ix = *New()
+	read, err := ix.docs.ReadFrom(r)
+	if err != nil {
+		return read, err
+	}
+	for doc := range ix.docs.Iterate() {
+		ix.terms[doc] = []string{}
+	}
+
+	buf := make([]byte, 8)
+	readFull := func(data []byte) error {
+		n, err := io.ReadFull(r, data)
+		read += int64(n)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if err := readFull(buf); err != nil {
+		return read, err
+	}
+	for terms := binary.LittleEndian.Uint64(buf); terms > 0; terms-- {
+		if err := readFull(buf); err != nil {
+			return read, err
+		}
+		length := binary.LittleEndian.Uint64(buf)
+		if length > 1<<30 {
+			return read, errors.New("index: term too long")
+		}
+		term := make([]byte, length)
+		if err := readFull(term); err != nil {
+			return read, err
+		}
+		if _, ok := ix.postings[string(term)]; ok {
+			return read, errors.New("index: duplicate term")
+		}
+
+		posting := sparsebitvector.New()
+		n, err := posting.ReadFrom(r)
+		read += n
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return read, err
+		}
+		if !ix.docs.Contains(posting) {
+			return read, errors.New("index: posting of unknown document")
+		}
+		if posting.Count() == 0 {
+			continue
+		}
+		posting.EnableSummary()
+		ix.postings[string(term)] = posting
+		for doc := range posting.Iterate() {
+			ix.terms[doc] = append(ix.terms[doc], string(term))
+		}
+	}
+	return read, nil
+}
+
+// MarshalBinary encodes ix in the format written by WriteTo.
+func (ix *Index) MarshalBinary() ([]byte, error) {
+	buf := bytes.Buffer{}
+	_, err := ix.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary replaces the contents of ix with data in the format written by WriteTo.
+func (ix *Index) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if _, err := ix.ReadFrom(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return errors.New("index: trailing data")
+	}
+	return nil
+}
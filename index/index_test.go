@@ -0,0 +1,83 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package index
+
+import (
+	"testing"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// corpus returns an index of a few tagged documents.
+func corpus() *Index {
+	ix := New()
+	ix.Add(1, "go", "fast", "compiled")
+	ix.Add(2, "python", "slow", "interpreted")
+	ix.Add(3, "go", "concurrent")
+	ix.Add(4, "rust", "fast", "compiled")
+	ix.Add(1<<40, "go", "fast")
+	ix.Add(5)
+	return ix
+}
+
+func TestIndex(t *testing.T) {
+	ix := corpus()
+	if ix.Postings("go").String() != "[1 3 1099511627776]" || ix.Postings("missing").Count() != 0 {
+		t.Error("incorrect postings", ix.Postings("go"))
+	}
+	if ix.Docs().String() != "[1 2 3 4 5 1099511627776]" || ix.Terms() != 8 {
+		t.Error("incorrect documents", ix.Docs(), ix.Terms())
+	}
+
+	ix.Add(3, "go", "fast")
+	if ix.Postings("fast").String() != "[1 3 4 1099511627776]" {
+		t.Error("incorrect added terms", ix.Postings("fast"))
+	}
+	if !ix.Remove(3) || ix.Remove(3) {
+		t.Error("incorrect remove")
+	}
+	if ix.Postings("go").String() != "[1 1099511627776]" || ix.Terms() != 7 || ix.Docs().Test(3) {
+		t.Error("incorrect postings after remove", ix.Postings("go"), ix.Terms())
+	}
+}
+
+func TestIndexBinary(t *testing.T) {
+	ix := corpus()
+	data, err := ix.MarshalBinary()
+	if err != nil {
+		t.Error("unexpected error", err)
+	}
+	loaded := New()
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	if !loaded.Docs().Equals(ix.Docs()) || loaded.Terms() != ix.Terms() {
+		t.Error("incorrect documents", loaded.Docs(), loaded.Terms())
+	}
+	for term, posting := range ix.postings {
+		if !loaded.Postings(term).Equals(posting) {
+			t.Error("incorrect posting", term, loaded.Postings(term))
+		}
+	}
+	if !loaded.Remove(1) || loaded.Postings("compiled").String() != "[4]" {
+		t.Error("incorrect loaded terms", loaded.Postings("compiled"))
+	}
+
+	if err := loaded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+	if err := loaded.UnmarshalBinary(append(data, 0)); err == nil {
+		t.Error("expected error for trailing data")
+	}
+
+	// a posting referring to a document outside the document vector
+	orphan := New()
+	orphan.Add(1, "go")
+	orphan.docs = sparsebitvector.New()
+	data, _ = orphan.MarshalBinary()
+	if err := loaded.UnmarshalBinary(data); err == nil {
+		t.Error("expected error for unknown document")
+	}
+}
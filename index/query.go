@@ -0,0 +1,191 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+type op int
+
+const (
+	termOp op = iota
+	andOp
+	orOp
+	notOp
+)
+
+// Query is a boolean query over the terms of an Index.
+type Query struct {
+	op       op
+	term     string
+	children []*Query
+}
+
+// Term matches the documents containing term.
+func Term(term string) *Query {
+	return &Query{op: termOp, term: term}
+}
+
+// And matches the documents matched by every query in qs.
+func And(qs ...*Query) *Query {
+	return &Query{op: andOp, children: qs}
+}
+
+// Or matches the documents matched by any query in qs.
+func Or(qs ...*Query) *Query {
+	return &Query{op: orOp, children: qs}
+}
+
+// Not matches the documents not matched by q.
+func Not(q *Query) *Query {
+	return &Query{op: notOp, children: []*Query{q}}
+}
+
+// estimate returns an upper bound on the number of documents matched by q.
+func (ix *Index) estimate(q *Query) int {
+	switch q.op {
+	case termOp:
+		return ix.Postings(q.term).Count()
+	case andOp:
+		result := ix.docs.Count()
+		for _, child := range q.children {
+			if child.op != notOp {
+				if n := ix.estimate(child); n < result {
+					result = n
+				}
+			}
+		}
+		return result
+	case orOp:
+		result := 0
+		for _, child := range q.children {
+			result += ix.estimate(child)
+		}
+		if result > ix.docs.Count() {
+			result = ix.docs.Count()
+		}
+		return result
+	default:
+		// the child's estimate is an upper bound, so it bounds nothing here
+		return ix.docs.Count()
+	}
+}
+
+// plan orders the children of an And by ascending estimate,
+// with negated children last, so that small postings drive the intersection
+// and differences apply to the smallest intermediate result.
+func (ix *Index) plan(q *Query) []*Query {
+	children := append([]*Query{}, q.children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		if (children[i].op == notOp) != (children[j].op == notOp) {
+			return children[j].op == notOp
+		}
+		return ix.estimate(children[i]) < ix.estimate(children[j])
+	})
+	return children
+}
+
+// operand returns the documents matched by q, without copying postings.
+// The result must not be modified unless q is not a Term.
+func (ix *Index) operand(q *Query) *sparsebitvector.SparseBitVector {
+	if q.op == termOp {
+		return ix.Postings(q.term)
+	}
+	return ix.Search(q)
+}
+
+// Search returns a new SparseBitVector of the documents matched by q.
+// Conjunctions of postings are intersected together, smallest first,
+// with each posting seeking directly to the next candidate document.
+func (ix *Index) Search(q *Query) *sparsebitvector.SparseBitVector {
+	switch q.op {
+	case termOp:
+		return sparsebitvector.UnionAll(ix.Postings(q.term))
+	case andOp:
+		children := ix.plan(q)
+		positive := []*sparsebitvector.SparseBitVector{}
+		for _, child := range children {
+			if child.op != notOp {
+				positive = append(positive, ix.operand(child))
+			}
+		}
+		if len(positive) == 0 {
+			positive = append(positive, ix.docs)
+		}
+		result := sparsebitvector.IntersectAll(positive...)
+		for _, child := range children {
+			if child.op == notOp && result.Count() != 0 {
+				result.IntersectWithComplement(ix.operand(child.children[0]))
+			}
+		}
+		return result
+	case orOp:
+		operands := []*sparsebitvector.SparseBitVector{}
+		for _, child := range q.children {
+			operands = append(operands, ix.operand(child))
+		}
+		return sparsebitvector.UnionAll(operands...)
+	default:
+		result := sparsebitvector.UnionAll(ix.docs)
+		result.IntersectWithComplement(ix.operand(q.children[0]))
+		return result
+	}
+}
+
+// Explain describes the plan Search uses for q, annotated with the estimated number of matches.
+func (ix *Index) Explain(q *Query) string {
+	var children []*Query
+	switch q.op {
+	case termOp:
+		return fmt.Sprintf("%q[%d]", q.term, ix.estimate(q))
+	case andOp:
+		children = ix.plan(q)
+	default:
+		children = q.children
+	}
+	parts := []string{}
+	for _, child := range children {
+		parts = append(parts, ix.Explain(child))
+	}
+	name := map[op]string{andOp: "AND", orOp: "OR", notOp: "NOT"}[q.op]
+	return fmt.Sprintf("%s(%s)[%d]", name, strings.Join(parts, " "), ix.estimate(q))
+}
+
+// Hit is a document along with the number of query terms it contains.
+type Hit struct {
+	Doc     sparsebitvector.KeyType
+	Matches int
+}
+
+// TopK returns up to k documents containing the most of terms,
+// in descending order of matches and then ascending document.
+// Documents are gathered by decreasing match count with AtLeast.
+func (ix *Index) TopK(k int, terms ...string) []Hit {
+	postings := []*sparsebitvector.SparseBitVector{}
+	for _, term := range terms {
+		if posting, ok := ix.postings[term]; ok {
+			postings = append(postings, posting)
+		}
+	}
+
+	result := []Hit{}
+	seen := sparsebitvector.New()
+	for n := len(postings); n > 0 && len(result) < k; n-- {
+		docs := sparsebitvector.AtLeast(n, postings...)
+		docs.IntersectWithComplement(seen)
+		for doc := range docs.Iterate() {
+			if len(result) < k {
+				result = append(result, Hit{doc, n})
+			}
+		}
+		seen.UnionWith(docs)
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	ix := corpus()
+	for _, test := range []struct {
+		q        *Query
+		expected string
+	}{
+		{Term("go"), "[1 3 1099511627776]"},
+		{Term("missing"), "[]"},
+		{And(Term("go"), Term("fast")), "[1 1099511627776]"},
+		{And(Term("fast"), Not(Term("go"))), "[4]"},
+		{Or(Term("python"), Term("rust")), "[2 4]"},
+		{Not(Term("fast")), "[2 3 5]"},
+		{And(Not(Term("go")), Not(Term("python"))), "[4 5]"},
+		{And(Or(Term("go"), Term("rust")), Term("compiled"), Not(Term("missing"))), "[1 4]"},
+		{And(Term("go"), Term("missing")), "[]"},
+	} {
+		if result := ix.Search(test.q); result.String() != test.expected {
+			t.Error("incorrect result", ix.Explain(test.q), result)
+		}
+	}
+	if ix.Postings("go").Count() != 3 {
+		t.Error("posting modified", ix.Postings("go"))
+	}
+}
+
+func TestExplain(t *testing.T) {
+	ix := corpus()
+	q := And(Not(Term("python")), Term("fast"), Or(Term("rust"), Term("slow")))
+	if plan := ix.Explain(q); plan != `AND(OR("rust"[1] "slow"[1])[2] "fast"[3] NOT("python"[1])[6])[2]` {
+		t.Error("incorrect plan", plan)
+	}
+
+	// a negated subquery is not estimated below the terms it is ordered against
+	q = And(Or(Term("rust"), Not(Or(Term("go"), Term("fast")))), Term("compiled"))
+	if plan := ix.Explain(q); plan != `AND("compiled"[2] OR("rust"[1] NOT(OR("go"[3] "fast"[3])[6])[6])[6])[2]` {
+		t.Error("incorrect plan", plan)
+	}
+}
+
+func TestTopK(t *testing.T) {
+	ix := corpus()
+	expected := []Hit{{1, 3}, {4, 2}, {1 << 40, 2}, {3, 1}}
+	if hits := ix.TopK(4, "go", "fast", "compiled", "missing"); !reflect.DeepEqual(hits, expected) {
+		t.Error("incorrect hits", hits)
+	}
+	if hits := ix.TopK(2, "go", "fast", "compiled"); !reflect.DeepEqual(hits, expected[:2]) {
+		t.Error("incorrect hits", hits)
+	}
+	if hits := ix.TopK(2, "missing"); len(hits) != 0 {
+		t.Error("unexpected hits", hits)
+	}
+}
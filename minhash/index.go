@@ -0,0 +1,124 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package minhash
+
+import (
+	"sort"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// Match is a vector found by a Query along with its exact Jaccard similarity.
+type Match struct {
+	ID      sparsebitvector.KeyType
+	Jaccard float64
+}
+
+// Index finds near duplicates among inserted vectors with banded locality sensitive hashing.
+// Signatures are split into bands, and vectors whose signatures agree on
+// every slot of any band become candidates, which are then verified exactly.
+type Index struct {
+	hasher  *Hasher
+	rows    int
+	buckets []map[uint64]*sparsebitvector.SparseBitVector // of IDs, per band
+	vecs    map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector
+}
+
+// NewIndex creates an empty Index using h's signatures split into the given number of bands,
+// which must divide the signature length.
+func NewIndex(h *Hasher, bands int) *Index {
+	if bands < 1 || h.Len()%bands != 0 {
+		panic("bands must divide the signature length")
+	}
+	ix := &Index{
+		hasher:  h,
+		rows:    h.Len() / bands,
+		buckets: make([]map[uint64]*sparsebitvector.SparseBitVector, bands),
+		vecs:    make(map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector),
+	}
+	for b := range ix.buckets {
+		ix.buckets[b] = make(map[uint64]*sparsebitvector.SparseBitVector)
+	}
+	return ix
+}
+
+// band returns the bucket key of band b of s.
+func (ix *Index) band(s Signature, b int) uint64 {
+	key := uint64(b)
+	for _, value := range s[b*ix.rows : (b+1)*ix.rows] {
+		key = mix(key ^ value)
+	}
+	return key
+}
+
+// Insert adds a copy of vec to ix under id, replacing any vector previously inserted under id.
+func (ix *Index) Insert(id sparsebitvector.KeyType, vec *sparsebitvector.SparseBitVector) {
+	ix.Remove(id)
+	s := ix.hasher.Signature(vec)
+	for b, buckets := range ix.buckets {
+		key := ix.band(s, b)
+		ids, ok := buckets[key]
+		if !ok {
+			ids = sparsebitvector.New()
+			buckets[key] = ids
+		}
+		ids.Set(id)
+	}
+	ix.vecs[id] = sparsebitvector.UnionAll(vec)
+}
+
+// Remove removes the vector inserted under id.
+// It returns true iff ix changed.
+func (ix *Index) Remove(id sparsebitvector.KeyType) bool {
+	vec, ok := ix.vecs[id]
+	if !ok {
+		return false
+	}
+	s := ix.hasher.Signature(vec)
+	for b, buckets := range ix.buckets {
+		key := ix.band(s, b)
+		if buckets[key].Unset(id); buckets[key].Count() == 0 {
+			delete(buckets, key)
+		}
+	}
+	delete(ix.vecs, id)
+	return true
+}
+
+// Len returns the number of vectors in ix.
+func (ix *Index) Len() int {
+	return len(ix.vecs)
+}
+
+// Candidates returns the IDs of vectors sharing at least one band with vec.
+func (ix *Index) Candidates(vec *sparsebitvector.SparseBitVector) *sparsebitvector.SparseBitVector {
+	s := ix.hasher.Signature(vec)
+	matches := []*sparsebitvector.SparseBitVector{}
+	for b, buckets := range ix.buckets {
+		if ids, ok := buckets[ix.band(s, b)]; ok {
+			matches = append(matches, ids)
+		}
+	}
+	return sparsebitvector.UnionAll(matches...)
+}
+
+// Query returns the candidates whose exact Jaccard similarity with vec is at least threshold,
+// in descending order of similarity and then ascending ID.
+// Vectors similar to vec but sharing no band with it are missed.
+func (ix *Index) Query(vec *sparsebitvector.SparseBitVector, threshold float64) []Match {
+	result := []Match{}
+	for id := range ix.Candidates(vec).Iterate() {
+		if j := ix.vecs[id].Jaccard(vec); j >= threshold {
+			result = append(result, Match{ID: id, Jaccard: j})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Jaccard != result[j].Jaccard {
+			return result[i].Jaccard > result[j].Jaccard
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
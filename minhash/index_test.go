@@ -0,0 +1,53 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package minhash
+
+import (
+	"testing"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+func TestIndex(t *testing.T) {
+	ix := NewIndex(NewHasher(64, 7), 16)
+
+	// unrelated vectors of 100 keys each
+	for id := sparsebitvector.KeyType(0); id < 50; id++ {
+		vec := sparsebitvector.New()
+		for i := sparsebitvector.KeyType(0); i < 100; i++ {
+			vec.Set(id*100000 + i*13)
+		}
+		ix.Insert(id, vec)
+	}
+	a, b := overlapping(900)
+	ix.Insert(100, a)
+	ix.Insert(101, b)
+	if ix.Len() != 52 {
+		t.Error("incorrect size", ix.Len())
+	}
+
+	matches := ix.Query(a, 0.5)
+	if len(matches) != 2 || matches[0].ID != 100 || matches[0].Jaccard != 1 || matches[1].ID != 101 || matches[1].Jaccard != a.Jaccard(b) {
+		t.Error("incorrect matches", matches)
+	}
+	if matches := ix.Query(a, 0.95); len(matches) != 1 {
+		t.Error("incorrect threshold", matches)
+	}
+
+	if !ix.Remove(100) || ix.Remove(100) || ix.Len() != 51 {
+		t.Error("incorrect remove", ix.Len())
+	}
+	if matches := ix.Query(a, 0.5); len(matches) != 1 || matches[0].ID != 101 {
+		t.Error("incorrect matches after remove", matches)
+	}
+
+	ix.Insert(101, sparsebitvector.New(1, 2, 3))
+	if matches := ix.Query(b, 0.5); len(matches) != 0 {
+		t.Error("stale vector", matches)
+	}
+	if matches := ix.Query(sparsebitvector.New(1, 2, 3, 4), 0.5); len(matches) != 1 || matches[0].Jaccard != 0.75 {
+		t.Error("incorrect matches after replace", matches)
+	}
+}
@@ -0,0 +1,131 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+// Package minhash estimates Jaccard similarity between SparseBitVectors
+// with MinHash signatures, and finds near duplicates with banded LSH.
+package minhash
+
+import (
+	"math"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// empty marks a signature slot which no key hashed to.
+const empty = math.MaxUint64
+
+// mix is the 64 bit finalizer from MurmurHash3, which scrambles h into a well distributed hash.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// Signature is a MinHash signature.
+type Signature []uint64
+
+// Similarity returns the fraction of slots in which s and s2 agree,
+// which estimates the Jaccard similarity of the vectors they were computed from.
+func (s Signature) Similarity(s2 Signature) float64 {
+	if len(s) != len(s2) {
+		panic("signature lengths differ")
+	}
+	equal := 0
+	for i := range s {
+		if s[i] == s2[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(s))
+}
+
+// Hasher computes MinHash signatures of a fixed length.
+type Hasher struct {
+	seeds          []uint64 // one per slot, or one in total for one-permutation signatures
+	k              int
+	onePermutation bool
+}
+
+// deriveSeeds returns n hash seeds derived from seed.
+func deriveSeeds(n int, seed uint64) []uint64 {
+	if n < 1 {
+		panic("signature length must be positive")
+	}
+	seeds := make([]uint64, n)
+	for i := range seeds {
+		seed += 0x9e3779b97f4a7c15
+		seeds[i] = mix(seed)
+	}
+	return seeds
+}
+
+// NewHasher creates a Hasher for k-permutation signatures with k slots,
+// each the minimum of an independent hash function over the true bits.
+func NewHasher(k int, seed uint64) *Hasher {
+	return &Hasher{seeds: deriveSeeds(k, seed), k: k}
+}
+
+// NewOnePermutationHasher creates a Hasher for one-permutation signatures with k slots,
+// which hash each true bit once into one of k bins and keep the minimum of each bin.
+// Empty bins are filled from the next non-empty bin by rotation.
+func NewOnePermutationHasher(k int, seed uint64) *Hasher {
+	if k < 1 {
+		panic("signature length must be positive")
+	}
+	return &Hasher{seeds: deriveSeeds(1, seed), k: k, onePermutation: true}
+}
+
+// Len returns the number of slots in h's signatures.
+func (h *Hasher) Len() int {
+	return h.k
+}
+
+// Signature returns the MinHash signature of vec.
+func (h *Hasher) Signature(vec *sparsebitvector.SparseBitVector) Signature {
+	s := make(Signature, h.k)
+	for i := range s {
+		s[i] = empty
+	}
+	vec.Blocks(func(index sparsebitvector.KeyType, block sparsebitvector.FiniteBitVector) {
+		for i := block.FindNext(0); i != -1; i = block.FindNext(i + 1) {
+			key := uint64(index)*sparsebitvector.ElementSize + uint64(i)
+			if h.onePermutation {
+				value := mix(key ^ h.seeds[0])
+				if bin := value % uint64(len(s)); value < s[bin] {
+					s[bin] = value
+				}
+				continue
+			}
+			for j, seed := range h.seeds {
+				if value := mix(key ^ seed); value < s[j] {
+					s[j] = value
+				}
+			}
+		}
+	})
+	if h.onePermutation {
+		densify(s)
+	}
+	return s
+}
+
+// densify fills each empty slot from the nearest originally non-empty slot
+// to its right, mixed with the distance so that borrowed values rarely agree by chance.
+func densify(s Signature) {
+	original := append(Signature(nil), s...)
+	for i := range s {
+		if s[i] != empty {
+			continue
+		}
+		for d := 1; d < len(s); d++ {
+			if value := original[(i+d)%len(s)]; value != empty {
+				s[i] = mix(value + uint64(d))
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package minhash
+
+import (
+	"math"
+	"testing"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// overlapping returns vectors of 1000 keys each whose Jaccard similarity is shared/(2000-shared).
+func overlapping(shared int) (*sparsebitvector.SparseBitVector, *sparsebitvector.SparseBitVector) {
+	a, b := sparsebitvector.New(), sparsebitvector.New()
+	for i := 0; i < 1000; i++ {
+		a.Set(sparsebitvector.KeyType(i * 7))
+		b.Set(sparsebitvector.KeyType((i + 1000 - shared) * 7))
+	}
+	return a, b
+}
+
+func TestSignature(t *testing.T) {
+	if h := NewOnePermutationHasher(512, 1); h.Len() != 512 || len(h.seeds) != 1 {
+		t.Error("unexpected one-permutation seeds", h.Len(), len(h.seeds))
+	}
+	for _, h := range []*Hasher{NewHasher(512, 1), NewOnePermutationHasher(512, 1)} {
+		a, b := overlapping(600)
+		exact := a.Jaccard(b)
+		if estimate := h.Signature(a).Similarity(h.Signature(b)); math.Abs(estimate-exact) > 0.1 {
+			t.Error("inaccurate estimate", h.onePermutation, estimate, exact)
+		}
+
+		c := sparsebitvector.NewAdaptive(8)
+		a.Blocks(func(index sparsebitvector.KeyType, block sparsebitvector.FiniteBitVector) {
+			for i := block.FindNext(0); i != -1; i = block.FindNext(i + 1) {
+				c.Set(index*sparsebitvector.ElementSize + sparsebitvector.KeyType(i))
+			}
+		})
+		if h.Signature(a).Similarity(h.Signature(c)) != 1 {
+			t.Error("unequal signatures of equal vectors", h.onePermutation)
+		}
+
+		disjoint, _ := overlapping(0)
+		disjoint.IntersectWithComplement(a)
+		disjoint.Set(1)
+		if s := h.Signature(a).Similarity(h.Signature(disjoint)); s > 0.05 {
+			t.Error("similar disjoint vectors", h.onePermutation, s)
+		}
+
+		for _, value := range h.Signature(sparsebitvector.New()) {
+			if value != empty {
+				t.Error("incorrect empty signature", h.onePermutation, value)
+			}
+		}
+	}
+
+	// the bins of a single key's one-permutation signature are all filled
+	for _, value := range NewOnePermutationHasher(16, 1).Signature(sparsebitvector.New(42)) {
+		if value == empty {
+			t.Error("undensified signature")
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+// Package mining finds frequent itemsets and association rules in transactions
+// stored vertically, as a SparseBitVector of transaction IDs per item.
+package mining
+
+import (
+	"sort"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// Itemset is a set of items along with the number of transactions containing all of them.
+type Itemset struct {
+	Items   *sparsebitvector.SparseBitVector
+	Support int
+}
+
+// member is an extension of the current prefix in the Eclat search,
+// holding either its tidset or its diffset relative to the prefix.
+type member struct {
+	item    sparsebitvector.KeyType
+	set     *sparsebitvector.SparseBitVector
+	support int
+}
+
+type miner struct {
+	minSupport int
+	diffsets   bool
+	result     []Itemset
+}
+
+// Eclat returns the itemsets contained in at least minSupport transactions,
+// given the transactions containing each item.
+// Supports are counted by intersecting tidsets depth first.
+func Eclat(items map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector, minSupport int) []Itemset {
+	return mine(items, minSupport, false)
+}
+
+// DEclat is like Eclat, but below the first level it tracks diffsets,
+// the transactions lost by extending a prefix, which shrink with depth on dense data.
+func DEclat(items map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector, minSupport int) []Itemset {
+	return mine(items, minSupport, true)
+}
+
+func mine(items map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector, minSupport int, diffsets bool) []Itemset {
+	if minSupport < 1 {
+		panic("minimum support must be positive")
+	}
+	m := &miner{minSupport: minSupport, diffsets: diffsets}
+
+	// extending with items in ascending order of support keeps classes small
+	class := []member{}
+	for item, tids := range items {
+		if support := tids.Count(); support >= minSupport {
+			class = append(class, member{item, tids, support})
+		}
+	}
+	sort.Slice(class, func(i, j int) bool {
+		if class[i].support != class[j].support {
+			return class[i].support < class[j].support
+		}
+		return class[i].item < class[j].item
+	})
+	m.extend(sparsebitvector.New(), class, false)
+
+	Sort(m.result)
+	return m.result
+}
+
+// extend emits each member of class joined to prefix, and recurses into its extensions.
+// The sets of class are diffsets iff diff is true.
+func (m *miner) extend(prefix *sparsebitvector.SparseBitVector, class []member, diff bool) {
+	for i, a := range class {
+		items := sparsebitvector.UnionAll(prefix, sparsebitvector.New(a.item))
+		m.result = append(m.result, Itemset{items, a.support})
+
+		next := []member{}
+		for _, b := range class[i+1:] {
+			var set *sparsebitvector.SparseBitVector
+			var support int
+			switch {
+			case !m.diffsets:
+				set = sparsebitvector.IntersectAll(a.set, b.set)
+				support = set.Count()
+			case !diff:
+				// d(ab) = t(a) − t(b)
+				set = sparsebitvector.UnionAll(a.set)
+				set.IntersectWithComplement(b.set)
+				support = a.support - set.Count()
+			default:
+				// d(Pab) = d(Pb) − d(Pa)
+				set = sparsebitvector.UnionAll(b.set)
+				set.IntersectWithComplement(a.set)
+				support = a.support - set.Count()
+			}
+			if support >= m.minSupport {
+				next = append(next, member{b.item, set, support})
+			}
+		}
+		if len(next) > 0 {
+			m.extend(items, next, m.diffsets)
+		}
+	}
+}
+
+// Sort orders sets by ascending size and then by their items.
+func Sort(sets []Itemset) {
+	sort.Slice(sets, func(i, j int) bool {
+		if sets[i].Items.Count() != sets[j].Items.Count() {
+			return sets[i].Items.Count() < sets[j].Items.Count()
+		}
+		return sparsebitvector.Cmp(sets[i].Items, sets[j].Items) < 0
+	})
+}
+
+// lookup finds itemsets by their items.
+type lookup map[uint64][]int
+
+func newLookup(sets []Itemset) lookup {
+	l := lookup{}
+	for i, set := range sets {
+		h := set.Items.Hash()
+		l[h] = append(l[h], i)
+	}
+	return l
+}
+
+// find returns the position of the itemset with the given items in sets, or -1.
+func (l lookup) find(sets []Itemset, items *sparsebitvector.SparseBitVector) int {
+	for _, i := range l[items.Hash()] {
+		if sets[i].Items.Equals(items) {
+			return i
+		}
+	}
+	return -1
+}
+
+// subsumed reports, for each of the frequent itemsets, whether it has a frequent
+// proper superset, and whether it has one with equal support.
+// Since supersets of an itemset with one more item suffice, only those are checked.
+func subsumed(sets []Itemset) (superset, equal []bool) {
+	superset = make([]bool, len(sets))
+	equal = make([]bool, len(sets))
+	l := newLookup(sets)
+	for _, set := range sets {
+		if set.Items.Count() < 2 {
+			continue
+		}
+		for item := range set.Items.Iterate() {
+			subset := sparsebitvector.UnionAll(set.Items)
+			subset.Unset(item)
+			if i := l.find(sets, subset); i != -1 {
+				superset[i] = true
+				equal[i] = equal[i] || sets[i].Support == set.Support
+			}
+		}
+	}
+	return superset, equal
+}
+
+// Closed returns the itemsets in sets which have no proper superset in sets with equal support.
+// Sets must hold all frequent itemsets, as returned by Eclat or DEclat.
+func Closed(sets []Itemset) []Itemset {
+	result := []Itemset{}
+	_, equal := subsumed(sets)
+	for i, set := range sets {
+		if !equal[i] {
+			result = append(result, set)
+		}
+	}
+	return result
+}
+
+// Maximal returns the itemsets in sets which have no proper superset in sets.
+// Sets must hold all frequent itemsets, as returned by Eclat or DEclat.
+func Maximal(sets []Itemset) []Itemset {
+	result := []Itemset{}
+	superset, _ := subsumed(sets)
+	for i, set := range sets {
+		if !superset[i] {
+			result = append(result, set)
+		}
+	}
+	return result
+}
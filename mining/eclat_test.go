@@ -0,0 +1,62 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package mining
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// transactions returns the vertical layout of the textbook example
+// with nine transactions over items 1 to 5.
+func transactions() map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector {
+	rows := [][]sparsebitvector.KeyType{{1, 2, 5}, {2, 4}, {2, 3}, {1, 2, 4}, {1, 3}, {2, 3}, {1, 3}, {1, 2, 3, 5}, {1, 2, 3}}
+	items := map[sparsebitvector.KeyType]*sparsebitvector.SparseBitVector{}
+	for tid, row := range rows {
+		for _, item := range row {
+			if items[item] == nil {
+				items[item] = sparsebitvector.New()
+			}
+			items[item].Set(sparsebitvector.KeyType(tid))
+		}
+	}
+	return items
+}
+
+func format(sets []Itemset) string {
+	result := []string{}
+	for _, set := range sets {
+		result = append(result, set.Items.String()+":"+string(rune('0'+set.Support)))
+	}
+	return strings.Join(result, " ")
+}
+
+func TestEclat(t *testing.T) {
+	expected := "[1]:6 [2]:7 [3]:6 [4]:2 [5]:2 [1 2]:4 [1 3]:4 [1 5]:2 [2 3]:4 [2 4]:2 [2 5]:2 [1 2 3]:2 [1 2 5]:2"
+	if sets := Eclat(transactions(), 2); format(sets) != expected {
+		t.Error("incorrect itemsets", format(sets))
+	}
+	if sets := DEclat(transactions(), 2); format(sets) != expected {
+		t.Error("incorrect diffset itemsets", format(sets))
+	}
+	if sets := DEclat(transactions(), 4); format(sets) != "[1]:6 [2]:7 [3]:6 [1 2]:4 [1 3]:4 [2 3]:4" {
+		t.Error("incorrect itemsets", format(sets))
+	}
+	if sets := Eclat(transactions(), 10); len(sets) != 0 {
+		t.Error("unexpected itemsets", format(sets))
+	}
+}
+
+func TestClosedAndMaximal(t *testing.T) {
+	sets := DEclat(transactions(), 2)
+	if closed := Closed(sets); format(closed) != "[1]:6 [2]:7 [3]:6 [1 2]:4 [1 3]:4 [2 3]:4 [2 4]:2 [1 2 3]:2 [1 2 5]:2" {
+		t.Error("incorrect closed itemsets", format(closed))
+	}
+	if maximal := Maximal(sets); format(maximal) != "[2 4]:2 [1 2 3]:2 [1 2 5]:2" {
+		t.Error("incorrect maximal itemsets", format(maximal))
+	}
+}
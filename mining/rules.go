@@ -0,0 +1,82 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package mining
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+// Rule is an association rule stating that transactions containing
+// Antecedent tend to also contain Consequent.
+type Rule struct {
+	Antecedent, Consequent *sparsebitvector.SparseBitVector
+	Support                int     // transactions containing both
+	Confidence             float64 // fraction of transactions with Antecedent which also have Consequent
+	Lift                   float64 // Confidence relative to the frequency of Consequent
+}
+
+// Rules returns the association rules with a confidence of at least minConfidence
+// which split an itemset of sets into a non-empty antecedent and consequent,
+// in descending order of confidence and then lift.
+// Sets must hold all frequent itemsets, as returned by Eclat or DEclat,
+// mined from the given number of transactions.
+// It returns an error if a subset of an itemset is missing from sets,
+// as for the output of Closed or Maximal, or if an itemset has 64 or more items.
+func Rules(sets []Itemset, transactions int, minConfidence float64) ([]Rule, error) {
+	l := newLookup(sets)
+	result := []Rule{}
+	for _, set := range sets {
+		items := []sparsebitvector.KeyType{}
+		for item := range set.Items.Iterate() {
+			items = append(items, item)
+		}
+		if len(items) < 2 {
+			continue
+		}
+		if len(items) >= 64 {
+			return nil, fmt.Errorf("mining: itemset of %d items is too large for rule generation", len(items))
+		}
+
+		// every proper subset of a frequent itemset is frequent
+		for mask := uint64(1); mask < 1<<uint(len(items))-1; mask++ {
+			antecedent, consequent := sparsebitvector.New(), sparsebitvector.New()
+			for i, item := range items {
+				if mask>>uint(i)&1 == 1 {
+					antecedent.Set(item)
+				} else {
+					consequent.Set(item)
+				}
+			}
+			a, c := l.find(sets, antecedent), l.find(sets, consequent)
+			if a == -1 || c == -1 {
+				return nil, fmt.Errorf("mining: subsets of itemset %v are missing", set.Items)
+			}
+			confidence := float64(set.Support) / float64(sets[a].Support)
+			if confidence < minConfidence {
+				continue
+			}
+			frequency := float64(sets[c].Support) / float64(transactions)
+			result = append(result, Rule{antecedent, consequent, set.Support, confidence, confidence / frequency})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		if a.Lift != b.Lift {
+			return a.Lift > b.Lift
+		}
+		if c := sparsebitvector.Cmp(a.Antecedent, b.Antecedent); c != 0 {
+			return c < 0
+		}
+		return sparsebitvector.Cmp(a.Consequent, b.Consequent) < 0
+	})
+	return result, nil
+}
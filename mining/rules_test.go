@@ -0,0 +1,47 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package mining
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/neilisaac/sparsebitvector"
+)
+
+func TestRules(t *testing.T) {
+	rules, err := Rules(Eclat(transactions(), 2), 9, 1)
+	if err != nil {
+		t.Error(err)
+	}
+	formatted := []string{}
+	for _, rule := range rules {
+		if rule.Confidence != 1 || rule.Support != 2 {
+			t.Error("incorrect rule", rule)
+		}
+		formatted = append(formatted, rule.Antecedent.String()+"=>"+rule.Consequent.String())
+	}
+	expected := "[5]=>[1 2] [2 5]=>[1] [5]=>[1] [1 5]=>[2] [4]=>[2] [5]=>[2]"
+	if strings.Join(formatted, " ") != expected {
+		t.Error("incorrect rules", formatted)
+	}
+	if len(rules) == 6 && (rules[0].Lift != 2.25 || math.Abs(rules[5].Lift-9.0/7) > 1e-12) {
+		t.Error("incorrect lift", rules[0].Lift, rules[5].Lift)
+	}
+
+	if rules, err := Rules(Eclat(transactions(), 2), 9, 0.5); err != nil || len(rules) != 16 {
+		t.Error("incorrect rule count", len(rules), err)
+	}
+
+	// maximal itemsets lack the subsets needed for confidence
+	if rules, err := Rules(Maximal(Eclat(transactions(), 2)), 9, 0.5); err == nil || rules != nil {
+		t.Error("missing subsets were not reported", rules)
+	}
+	large := sparsebitvector.NewRange(0, 63)
+	if _, err := Rules([]Itemset{{Items: large, Support: 1}}, 1, 0.5); err == nil {
+		t.Error("large itemset was not reported")
+	}
+}
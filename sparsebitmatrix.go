@@ -0,0 +1,339 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SparseBitMatrix is a sparse boolean matrix whose rows are SparseBitVectors.
+// It represents a relation between row and column keys.
+type SparseBitMatrix struct {
+	rows map[KeyType]*SparseBitVector
+	keys *SparseBitVector // rows which may be non-empty
+}
+
+// NewSparseBitMatrix creates an empty SparseBitMatrix.
+func NewSparseBitMatrix() *SparseBitMatrix {
+	return &SparseBitMatrix{rows: make(map[KeyType]*SparseBitVector), keys: New()}
+}
+
+// Set sets the bit at row r and column c to true.
+// It returns true iff the bit was changed.
+func (m *SparseBitMatrix) Set(r, c KeyType) bool {
+	return m.row(r).Set(c)
+}
+
+// Unset sets the bit at row r and column c to false.
+// It returns true iff the bit was changed.
+func (m *SparseBitMatrix) Unset(r, c KeyType) bool {
+	row, ok := m.rows[r]
+	if !ok {
+		return false
+	}
+	changed := row.Unset(c)
+	if row.Count() == 0 {
+		m.removeRow(r)
+	}
+	return changed
+}
+
+// Test checks whether the bit at row r and column c is true.
+func (m *SparseBitMatrix) Test(r, c KeyType) bool {
+	row, ok := m.rows[r]
+	return ok && row.Test(c)
+}
+
+// Row returns the columns of row r.
+// The result is shared with m and must not be modified.
+func (m *SparseBitMatrix) Row(r KeyType) *SparseBitVector {
+	if row, ok := m.rows[r]; ok {
+		return row
+	}
+	return New()
+}
+
+// Rows returns the keys of the non-empty rows.
+func (m *SparseBitMatrix) Rows() *SparseBitVector {
+	return m.keys.clone()
+}
+
+// Count returns the number of true bits.
+func (m *SparseBitMatrix) Count() int {
+	count := 0
+	for _, row := range m.rows {
+		count += row.Count()
+	}
+	return count
+}
+
+// Clear sets all bits to false.
+func (m *SparseBitMatrix) Clear() {
+	m.rows = make(map[KeyType]*SparseBitVector)
+	m.keys.Clear()
+}
+
+// Equals returns true iff m and m2 contain equivalent true bits.
+func (m *SparseBitMatrix) Equals(m2 *SparseBitMatrix) bool {
+	if !m.keys.Equals(m2.keys) {
+		return false
+	}
+	for r, row := range m.rows {
+		if !row.Equals(m2.rows[r]) {
+			return false
+		}
+	}
+	return true
+}
+
+// row returns row r, creating it if necessary.
+func (m *SparseBitMatrix) row(r KeyType) *SparseBitVector {
+	row, ok := m.rows[r]
+	if !ok {
+		row = New()
+		m.rows[r] = row
+		m.keys.Set(r)
+	}
+	return row
+}
+
+func (m *SparseBitMatrix) removeRow(r KeyType) {
+	delete(m.rows, r)
+	m.keys.Unset(r)
+}
+
+// Transpose returns a new SparseBitMatrix with the rows and columns of m swapped.
+func (m *SparseBitMatrix) Transpose() *SparseBitMatrix {
+	result := NewSparseBitMatrix()
+	for r, ok := m.keys.NextSet(0); ok; r, ok = m.keys.NextSet(r + 1) {
+		m.rows[r].blocks(func(index KeyType, vec *FiniteBitVector) {
+			for i := vec.FindNext(0); i != -1; i = vec.FindNext(i + 1) {
+				result.Set(index*ElementSize+KeyType(i), r)
+			}
+		})
+	}
+	return result
+}
+
+// Multiply returns the boolean matrix product of m and m2,
+// which is the composition of the relations m and then m2.
+func (m *SparseBitMatrix) Multiply(m2 *SparseBitMatrix) *SparseBitMatrix {
+	result := NewSparseBitMatrix()
+	for r, row := range m.rows {
+		rows := []*SparseBitVector{}
+		for k, ok := row.NextSet(0); ok; k, ok = row.NextSet(k + 1) {
+			if row2, ok := m2.rows[k]; ok {
+				rows = append(rows, row2)
+			}
+		}
+		if product := UnionAll(rows...); product.Count() != 0 {
+			result.rows[r] = product
+			result.keys.Set(r)
+		}
+	}
+	return result
+}
+
+// UnionWith sets m to the union of itself and m2.
+// It returns true iff m changed, along with the number of bits added.
+func (m *SparseBitMatrix) UnionWith(m2 *SparseBitMatrix) (bool, int) {
+	added := 0
+	for r, row2 := range m2.rows {
+		_, n := m.row(r).UnionWith(row2)
+		added += n
+		if m.rows[r].Count() == 0 {
+			m.removeRow(r)
+		}
+	}
+	return added != 0, added
+}
+
+// TransitiveClosure returns a new SparseBitMatrix relating each row to every
+// key reachable from it through one or more steps of m.
+// Strongly connected components are found first so that each cycle's
+// reachable set is only computed once.
+func (m *SparseBitMatrix) TransitiveClosure() *SparseBitMatrix {
+	// Tarjan's algorithm yields components with their successors first
+	index := map[KeyType]int{}
+	lowlink := map[KeyType]int{}
+	component := map[KeyType]int{}
+	reach := []*SparseBitVector{}
+	stack := []KeyType{}
+	onStack := New()
+
+	// frame is a row being visited, with the first column not yet examined
+	type frame struct {
+		r, next KeyType
+	}
+	frames := []frame{}
+	open := func(r KeyType) {
+		index[r] = len(index)
+		lowlink[r] = index[r]
+		stack = append(stack, r)
+		onStack.Set(r)
+		frames = append(frames, frame{r: r})
+	}
+
+	for root, ok := m.keys.NextSet(0); ok; root, ok = m.keys.NextSet(root + 1) {
+		if _, seen := index[root]; seen {
+			continue
+		}
+		open(root)
+		for len(frames) > 0 {
+			f := &frames[len(frames)-1]
+			if c, ok := m.Row(f.r).NextSet(f.next); ok {
+				f.next = c + 1
+				if _, seen := index[c]; !seen {
+					open(c)
+				} else if onStack.Test(c) && index[c] < lowlink[f.r] {
+					lowlink[f.r] = index[c]
+				}
+				continue
+			}
+
+			// every column was examined, so return to the parent
+			r := f.r
+			frames = frames[:len(frames)-1]
+			if len(frames) > 0 {
+				if parent := frames[len(frames)-1].r; lowlink[r] < lowlink[parent] {
+					lowlink[parent] = lowlink[r]
+				}
+			}
+			if lowlink[r] != index[r] {
+				continue
+			}
+
+			// pop the component and union its rows with the closures of its successors
+			id := len(reach)
+			members := []*SparseBitVector{}
+			for {
+				member := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack.Unset(member)
+				component[member] = id
+				members = append(members, m.Row(member))
+				if member == r {
+					break
+				}
+			}
+			closure := UnionAll(members...)
+			successors := New()
+			for c, ok := closure.NextSet(0); ok; c, ok = closure.NextSet(c + 1) {
+				if component[c] != id {
+					successors.Set(KeyType(component[c]))
+				}
+			}
+			for s, ok := successors.NextSet(0); ok; s, ok = successors.NextSet(s + 1) {
+				closure.UnionWith(reach[s])
+			}
+			reach = append(reach, closure)
+		}
+	}
+
+	result := NewSparseBitMatrix()
+	for r, ok := m.keys.NextSet(0); ok; r, ok = m.keys.NextSet(r + 1) {
+		if closure := reach[component[r]]; closure.Count() != 0 {
+			result.rows[r] = closure.clone()
+			result.keys.Set(r)
+		}
+	}
+	return result
+}
+
+// WriteTo writes m to w as a little-endian uint64 row count followed by
+// each row's key and its SparseBitVector in the binary block format.
+func (m *SparseBitMatrix) WriteTo(w io.Writer) (int64, error) {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(m.keys.Count()))
+	n, err := w.Write(buf)
+	written := int64(n)
+
+	for r := range m.keys.Iterate() {
+		if err != nil {
+			continue
+		}
+		binary.LittleEndian.PutUint64(buf, uint64(r))
+		if n, err = w.Write(buf); err == nil {
+			var n64 int64
+			n64, err = m.rows[r].WriteTo(w)
+			written += n64
+		}
+		written += int64(n)
+	}
+	return written, err
+}
+
+// ReadFrom replaces the contents of m with a matrix read from r in the format written by WriteTo.
+func (m *SparseBitMatrix) ReadFrom(r io.Reader) (int64, error) {
+	m.Clear()
+	buf := make([]byte, 8)
+	n, err := io.ReadFull(r, buf)
+	read := int64(n)
+	if err != nil {
+		return read, err
+	}
+
+	for rows := binary.LittleEndian.Uint64(buf); rows > 0; rows-- {
+		n, err = io.ReadFull(r, buf)
+		read += int64(n)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return read, err
+		}
+
+		key := KeyType(binary.LittleEndian.Uint64(buf))
+		if _, ok := m.rows[key]; ok {
+			return read, errors.New("sparsebitvector: duplicate row")
+		}
+		row := New()
+		n64, err := row.ReadFrom(r)
+		read += n64
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return read, err
+		}
+		if row.Count() != 0 {
+			m.rows[key] = row
+			m.keys.Set(key)
+		}
+	}
+	return read, nil
+}
+
+// MarshalBinary encodes m in the format written by WriteTo.
+func (m *SparseBitMatrix) MarshalBinary() ([]byte, error) {
+	buf := bytes.Buffer{}
+	_, err := m.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary replaces the contents of m with data in the format written by WriteTo.
+func (m *SparseBitMatrix) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if _, err := m.ReadFrom(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return errors.New("sparsebitvector: trailing data")
+	}
+	return nil
+}
+
+func (m *SparseBitMatrix) String() string {
+	rows := []string{}
+	for r := range m.keys.Iterate() {
+		rows = append(rows, fmt.Sprint(r, ":", m.rows[r]))
+	}
+	return "{" + strings.Join(rows, " ") + "}"
+}
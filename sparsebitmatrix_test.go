@@ -0,0 +1,130 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSparseBitMatrixOperation(t *testing.T) {
+	m := NewSparseBitMatrix()
+	if !m.Set(1, 2) || m.Set(1, 2) || !m.Set(1, 1000) || !m.Set(1<<40, 3) {
+		t.Error("incorrect set", m)
+	}
+	if !m.Test(1, 2) || m.Test(2, 1) || m.Test(5, 5) {
+		t.Error("incorrect test", m)
+	}
+	if m.Count() != 3 || m.String() != "{1:[2 1000] 1099511627776:[3]}" {
+		t.Error("incorrect contents", m)
+	}
+	if m.Row(1).String() != "[2 1000]" || m.Row(7).Count() != 0 {
+		t.Error("incorrect row", m.Row(1), m.Row(7))
+	}
+	if m.Rows().String() != "[1 1099511627776]" {
+		t.Error("incorrect rows", m.Rows())
+	}
+
+	if !m.Unset(1<<40, 3) || m.Unset(1<<40, 3) || m.Rows().String() != "[1]" {
+		t.Error("incorrect unset", m)
+	}
+	m.Clear()
+	if m.Count() != 0 || m.String() != "{}" {
+		t.Error("not empty", m)
+	}
+}
+
+func TestSparseBitMatrixAlgebra(t *testing.T) {
+	m := NewSparseBitMatrix()
+	m.Set(0, 1)
+	m.Set(0, 2)
+	m.Set(1, 3)
+	m.Set(2, 3)
+	m.Set(3, 4)
+
+	if tr := m.Transpose(); tr.String() != "{1:[0] 2:[0] 3:[1 2] 4:[3]}" {
+		t.Error("incorrect transpose", tr)
+	}
+	if tr := m.Transpose().Transpose(); !tr.Equals(m) {
+		t.Error("incorrect double transpose", tr)
+	}
+	if p := m.Multiply(m); p.String() != "{0:[3] 1:[4] 2:[4]}" {
+		t.Error("incorrect product", p)
+	}
+
+	m2 := NewSparseBitMatrix()
+	m2.Set(0, 1)
+	m2.Set(9, 9)
+	if changed, n := m2.UnionWith(m); !changed || n != 4 || m2.String() != "{0:[1 2] 1:[3] 2:[3] 3:[4] 9:[9]}" {
+		t.Error("incorrect union", changed, n, m2)
+	}
+	if changed, n := m2.UnionWith(m); changed || n != 0 {
+		t.Error("incorrect union", changed, n, m2)
+	}
+}
+
+func TestTransitiveClosure(t *testing.T) {
+	m := NewSparseBitMatrix()
+	if c := m.TransitiveClosure(); c.Count() != 0 {
+		t.Error("incorrect closure", c)
+	}
+
+	// 0 -> 1 -> 2 -> 1 is a cycle, 2 -> 3 -> 4, and 5 -> 5 is a self-loop
+	m.Set(0, 1)
+	m.Set(1, 2)
+	m.Set(2, 1)
+	m.Set(2, 3)
+	m.Set(3, 4)
+	m.Set(5, 5)
+	c := m.TransitiveClosure()
+	if c.String() != "{0:[1 2 3 4] 1:[1 2 3 4] 2:[1 2 3 4] 3:[4] 5:[5]}" {
+		t.Error("incorrect closure", c)
+	}
+
+	// closing again changes nothing
+	if c2 := c.TransitiveClosure(); !c2.Equals(c) {
+		t.Error("incorrect closure of closure", c2)
+	}
+	// the result must not share rows
+	c.Set(0, 100)
+	if c.Test(1, 100) {
+		t.Error("rows are shared", c)
+	}
+
+	// a long cycle is a single component
+	long := NewSparseBitMatrix()
+	for r := KeyType(0); r < 2000; r++ {
+		long.Set(r, (r+1)%2000)
+	}
+	if c := long.TransitiveClosure(); c.Count() != 2000*2000 || !c.Row(1000).Equals(NewRange(0, 1999)) {
+		t.Error("incorrect closure of long cycle", c.Count())
+	}
+}
+
+func TestSparseBitMatrixBinary(t *testing.T) {
+	m := NewSparseBitMatrix()
+	m.Set(0, 1)
+	m.Set(0, 1000)
+	m.Set(1<<50, 7)
+
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Error(err)
+	}
+	result := NewSparseBitMatrix()
+	result.Set(3, 3)
+	if err := result.UnmarshalBinary(data); err != nil || !result.Equals(m) {
+		t.Error("incorrect round trip", result, err)
+	}
+	if err := result.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("expected error")
+	}
+
+	// rows are stored in the vector binary format
+	row, _ := m.Row(0).MarshalBinary()
+	if !bytes.Equal(data[16:16+len(row)], row) {
+		t.Error("unexpected row encoding")
+	}
+}
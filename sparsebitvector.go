@@ -12,9 +12,17 @@ type KeyType uint64
 // SparseBitVector implementation based on that from LLVM:
 // https://github.com/llvm-mirror/llvm/blob/master/include/llvm/ADT/SparseBitVector.h
 type SparseBitVector struct {
-	start   *element
-	current *element
-	count   int
+	start     *element
+	current   *element
+	count     int
+	threshold int
+	arrays    []arrayContainer
+	summary   *summary
+	hops      int
+	last      *element
+	size      int
+	spill     *spill
+	hash      uint64 // XOR of the hashes of the blocks
 }
 
 // New creates and instance of a SparseBitVector, optionally initialized by set.
@@ -26,55 +34,107 @@ func New(set ...KeyType) *SparseBitVector {
 	return result
 }
 
+// NewRange creates a SparseBitVector with the bits from lo to hi inclusive set to true.
+func NewRange(lo, hi KeyType) *SparseBitVector {
+	result := New()
+	for lo <= hi {
+		index := lo / ElementSize
+		end := index*ElementSize + (ElementSize - 1)
+		if end > hi {
+			end = hi
+		}
+		block := FiniteBitVector{}
+		for i := lo % ElementSize; i <= end%ElementSize; i++ {
+			block.Set(uint(i))
+		}
+		result.push(index, &block)
+		if end == hi {
+			break
+		}
+		lo = end + 1
+	}
+	return result
+}
+
 // Set sets a particular bit to true in a SparseBitVector.
-func (sbv *SparseBitVector) Set(key KeyType) {
+// It returns true iff the bit was changed.
+func (sbv *SparseBitVector) Set(key KeyType) bool {
+	if sbv.spill != nil {
+		defer sbv.enforce()
+	}
 	index := key / ElementSize
-	nearest := sbv.search(index)
-
-	if nearest == nil {
-		e := sbv.create(index, nil, nil)
-		e.Set(uint(key % ElementSize))
-		sbv.count++
-	} else if nearest.index < index {
-		e := sbv.create(index, nearest, nearest.next)
-		e.Set(uint(key % ElementSize))
-		sbv.count++
-		nearest.next = e
-		if e.next != nil {
-			e.next.prev = e
+	if sbv.threshold > 0 {
+		if e := sbv.search(index); e == nil || e.index != index {
+			before := sbv.arrayBlock(index)
+			if !sbv.setArray(key) {
+				return false
+			}
+			after := before
+			after.Set(uint(key % ElementSize))
+			sbv.rehash(index, &before, &after)
+			return true
 		}
-	} else if nearest.index > index {
-		e := sbv.create(index, nearest.prev, nearest)
-		e.Set(uint(key % ElementSize))
+	}
+	e := sbv.insert(index)
+	before := e.FiniteBitVector
+	if e.TestAndSet(uint(key % ElementSize)) {
 		sbv.count++
-	} else {
-		if nearest.TestAndSet(uint(key % ElementSize)) {
-			sbv.count++
-		}
+		sbv.rehash(index, &before, &e.FiniteBitVector)
+		return true
 	}
+	return false
 }
 
 // Unset sets a particular bit to false.
-func (sbv *SparseBitVector) Unset(key KeyType) {
+// It returns true iff the bit was changed.
+func (sbv *SparseBitVector) Unset(key KeyType) bool {
+	if sbv.spill != nil {
+		defer sbv.enforce()
+	}
 	index := key / ElementSize
 	e := sbv.search(index)
 	if e == nil || e.index != index {
-		return
+		if sbv.threshold == 0 {
+			return false
+		}
+		before := sbv.arrayBlock(index)
+		if !sbv.unsetArray(key) {
+			return false
+		}
+		after := before
+		after.Unset(uint(key % ElementSize))
+		sbv.rehash(index, &before, &after)
+		return true
 	}
 
-	if e.TestAndUnset(uint(key % ElementSize)) {
+	before := e.FiniteBitVector
+	changed := e.TestAndUnset(uint(key % ElementSize))
+	if changed {
 		sbv.count--
+		sbv.rehash(index, &before, &e.FiniteBitVector)
 	}
-	if e.Count() == 0 {
-		sbv.delete(e)
-	}
+	sbv.fit(e)
+	return changed
 }
 
 // Clear sets all bits to false.
-func (sbv *SparseBitVector) Clear() {
+// It returns true iff sbv changed, along with the number of bits removed.
+func (sbv *SparseBitVector) Clear() (bool, int) {
+	removed := sbv.count
+	sbv.hash = 0
 	sbv.start = nil
 	sbv.current = nil
+	sbv.last = nil
 	sbv.count = 0
+	sbv.size = 0
+	sbv.arrays = nil
+	if sbv.summary != nil {
+		sbv.summary = newSummary()
+	}
+	if sbv.spill != nil {
+		sbv.spill.reset()
+	}
+	return removed != 0, removed
 }
 
 // Count returns the number of distinct bits that are true.
@@ -84,40 +144,114 @@ func (sbv *SparseBitVector) Count() int {
 
 // Test checks whether a particular bit is true.
 func (sbv *SparseBitVector) Test(key KeyType) bool {
+	if sbv.spill != nil {
+		defer sbv.enforce()
+	}
 	index := key / ElementSize
 	element := sbv.search(index)
 	if element == nil || element.index != index {
-		return false
+		return sbv.threshold > 0 && sbv.testArray(key)
 	}
 	return element.Test(uint(key % ElementSize))
 }
 
 // TestAndSet checks whether a bit was previously true before setting it to true.
 func (sbv *SparseBitVector) TestAndSet(key KeyType) bool {
-	if sbv.Test(key) {
-		return false
+	return sbv.Set(key)
+}
+
+// NextSet returns the first true bit at or after key, if any.
+func (sbv *SparseBitVector) NextSet(key KeyType) (KeyType, bool) {
+	if sbv.spill != nil {
+		defer sbv.enforce()
 	}
-	sbv.Set(key)
-	return true
+	for {
+		result, ok := sbv.findNext(key)
+		// page in a spilled range which may hold an earlier bit
+		if r := sbv.nextRange(key / ElementSize); r != -1 && (!ok || sbv.spill.ranges[r].lo <= result/ElementSize) {
+			sbv.pageIn(r)
+			continue
+		}
+		return result, ok
+	}
+}
+
+// PrevSet returns the last true bit at or before key, if any.
+func (sbv *SparseBitVector) PrevSet(key KeyType) (KeyType, bool) {
+	if sbv.spill != nil {
+		defer sbv.enforce()
+	}
+	for {
+		result, ok := sbv.findPrev(key)
+		// page in a spilled range which may hold a later bit
+		if r := sbv.prevRange(key / ElementSize); r != -1 && (!ok || sbv.spill.ranges[r].hi >= result/ElementSize) {
+			sbv.pageIn(r)
+			continue
+		}
+		return result, ok
+	}
+}
+
+// findNext returns the first true bit at or after key which is not spilled.
+func (sbv *SparseBitVector) findNext(key KeyType) (KeyType, bool) {
+	result, ok := sbv.nextArray(key)
+	index := key / ElementSize
+	e := sbv.search(index)
+	if e != nil && e.index < index {
+		e = e.next
+	}
+	for ; e != nil && (!ok || e.index*ElementSize < result); e = e.next {
+		i := 0
+		if e.index == index {
+			i = int(key % ElementSize)
+		}
+		if i = e.FindNext(i); i != -1 {
+			return e.index*ElementSize + KeyType(i), true
+		}
+	}
+	return result, ok
+}
+
+// findPrev returns the last true bit at or before key which is not spilled.
+func (sbv *SparseBitVector) findPrev(key KeyType) (KeyType, bool) {
+	result, ok := sbv.prevArray(key)
+	index := key / ElementSize
+	e := sbv.search(index)
+	if e != nil && e.index > index {
+		e = e.prev
+	}
+	for ; e != nil && (!ok || e.index*ElementSize+ElementSize-1 > result); e = e.prev {
+		i := ElementSize - 1
+		if e.index == index {
+			i = int(key % ElementSize)
+		}
+		if i = e.FindPrev(i); i != -1 {
+			return e.index*ElementSize + KeyType(i), true
+		}
+	}
+	return result, ok
 }
 
 // Equals returns true iff sbv and sbv2 contain equivalent true bits.
 func (sbv *SparseBitVector) Equals(sbv2 *SparseBitVector) bool {
-	for e1, e2 := sbv.start, sbv2.start; e1 != nil || e2 != nil; e1, e2 = e1.next, e2.next {
-		if e1 == nil || e2 == nil || e1.index != e2.index || !e1.Equals(&e2.FiniteBitVector) {
+	if sbv.count != sbv2.count || sbv.hash != sbv2.hash {
+		return false
+	}
+	for c1, c2 := sbv.cursor(), sbv2.cursor(); c1.valid || c2.valid; c1.next() {
+		if !c1.valid || !c2.valid || c1.index != c2.index || !c1.vec().Equals(c2.vec()) {
 			return false
 		}
+		c2.next()
 	}
 	return true
 }
 
 // Contains returns true iff sbv contains all of sbv2's true bits.
 func (sbv *SparseBitVector) Contains(sbv2 *SparseBitVector) bool {
-	for e1, e2 := sbv.start, sbv2.start; e2 != nil; e1, e2 = e1.next, e2.next {
-		for e1 != nil && e1.index < e2.index {
-			e1 = e1.next
-		}
-		if e1 == nil || e1.index != e2.index || !e1.Contains(&e2.FiniteBitVector) {
+	c1 := sbv.cursor()
+	for c2 := sbv2.cursor(); c2.valid; c2.next() {
+		c1.seek(c2.index)
+		if !c1.valid || c1.index != c2.index || !c1.vec().Contains(c2.vec()) {
 			return false
 		}
 	}
@@ -126,24 +260,40 @@ func (sbv *SparseBitVector) Contains(sbv2 *SparseBitVector) bool {
 
 // UnionAndIntersectionSize returns the number of true bits of the union and intersection with sbv2.
 func (sbv *SparseBitVector) UnionAndIntersectionSize(sbv2 *SparseBitVector) (int, int) {
+	u, i, _ := sbv.unionAndIntersectionSize(sbv2, 0)
+	return u, i
+}
+
+// unionAndIntersectionSize is like UnionAndIntersectionSize, but returns false
+// as soon as the intersection cannot reach need, leaving the sizes incomplete.
+func (sbv *SparseBitVector) unionAndIntersectionSize(sbv2 *SparseBitVector, need int) (int, int, bool) {
 	intersection := 0
-	for e1, e2 := sbv.start, sbv2.start; e1 != nil && e2 != nil; {
-		// sbv catch-up
-		for e1 != nil && (e2 == nil || e1.index < e2.index) {
-			e1 = e1.next
-		}
-		// sbv2 catch-up
-		for e2 != nil && (e1 == nil || e2.index < e1.index) {
-			e2 = e2.next
+	rest1, rest2 := sbv.count, sbv2.count // bits not yet visited
+	for c1, c2 := sbv.cursor(), sbv2.cursor(); c1.valid && c2.valid; {
+		if intersection+rest1 < need || intersection+rest2 < need {
+			return sbv.count + sbv2.count - intersection, intersection, false
 		}
-		// same index
-		if e1 != nil && e2 != nil && e1.index == e2.index {
-			intersection += e1.IntersectionSize(&e2.FiniteBitVector)
-			e1 = e1.next
-			e2 = e2.next
+		switch {
+		// without a bound, skip straight to the other's index
+		case c1.index < c2.index && need == 0:
+			c1.seek(c2.index)
+		case c1.index > c2.index && need == 0:
+			c2.seek(c1.index)
+		case c1.index < c2.index:
+			rest1 -= c1.vec().Count()
+			c1.next()
+		case c1.index > c2.index:
+			rest2 -= c2.vec().Count()
+			c2.next()
+		default:
+			intersection += c1.vec().IntersectionSize(c2.vec())
+			rest1 -= c1.vec().Count()
+			rest2 -= c2.vec().Count()
+			c1.next()
+			c2.next()
 		}
 	}
-	return sbv.count + sbv2.count - intersection, intersection
+	return sbv.count + sbv2.count - intersection, intersection, intersection >= need
 }
 
 // UnionSize returns the number of true bits of the union with sbv2.
@@ -158,78 +308,182 @@ func (sbv *SparseBitVector) IntersectionSize(sbv2 *SparseBitVector) int {
 	return i
 }
 
-// UnionWith returns the number of true bits of the union and intersection with sbv2.
-func (sbv *SparseBitVector) UnionWith(sbv2 *SparseBitVector) {
-	for e1, e2 := sbv.start, sbv2.start; e1 != nil || e2 != nil; {
+// UnionWith sets sbv to the union of itself and sbv2.
+// It returns true iff sbv changed, along with the number of bits added.
+func (sbv *SparseBitVector) UnionWith(sbv2 *SparseBitVector) (bool, int) {
+	return sbv.unionWith(sbv2, nil)
+}
+
+// UnionWithFunc is like UnionWith, but also calls fn with each bit added.
+func (sbv *SparseBitVector) UnionWithFunc(sbv2 *SparseBitVector, fn func(KeyType)) (bool, int) {
+	return sbv.unionWith(sbv2, fn)
+}
+
+func (sbv *SparseBitVector) unionWith(sbv2 *SparseBitVector, fn func(KeyType)) (bool, int) {
+	sbv.pageAll()
+	defer sbv.enforce()
+	added := 0
+	e1 := sbv.start
+	for c2 := sbv2.cursor(); c2.valid; c2.next() {
 		// sbv catch-up
-		for e1 != nil && (e2 == nil || e1.index < e2.index) {
-			e1 = e1.next
-		}
-		// sbv2 catch-up
-		for e2 != nil && (e1 == nil || e2.index < e1.index) {
-			// insert element and copy data
-			sbv.Set(e2.index * ElementSize)
-			e1 = sbv.search(e2.index)
-			e1.FiniteBitVector = e2.FiniteBitVector
-			sbv.count += e1.Count() - 1
-			e1 = e1.next
-			e2 = e2.next
+		e1 = sbv.seek(e1, c2.index)
+		if e1 == nil || e1.index != c2.index {
+			// merge with any array block, and store it in the array or a new element
+			before := sbv.arrayBlock(c2.index)
+			after := before
+			after.UnionWith(c2.vec())
+			added += after.Count() - before.Count()
+			sbv.rehash(c2.index, &before, &after)
+			report(c2.index, &before, &after, fn)
+			if e := sbv.storeBlock(c2.index, &after); e != nil {
+				e1 = e.next
+			}
+			continue
 		}
+
 		// same index
-		if e1 != nil && e2 != nil && e1.index == e2.index {
-			before := e1.Count()
-			e1.UnionWith(&e2.FiniteBitVector)
-			sbv.count += e1.Count() - before
-			e1 = e1.next
-			e2 = e2.next
-		}
+		before := e1.FiniteBitVector
+		e1.UnionWith(c2.vec())
+		added += e1.Count() - before.Count()
+		sbv.rehash(e1.index, &before, &e1.FiniteBitVector)
+		report(e1.index, &before, &e1.FiniteBitVector, fn)
+		e1 = e1.next
 	}
+	sbv.count += added
+	return added != 0, added
 }
 
 // IntersectWith sets sbv to the intersection of itself and sbv2.
-func (sbv *SparseBitVector) IntersectWith(sbv2 *SparseBitVector) {
-	for e1, e2 := sbv.start, sbv2.start; e1 != nil; {
-		// remove sbv elements not in sbv2
-		for e1 != nil && (e2 == nil || e1.index < e2.index) {
-			sbv.count -= e1.Count()
-			sbv.delete(e1)
-			e1 = e1.next
+// It returns true iff sbv changed, along with the number of bits removed.
+func (sbv *SparseBitVector) IntersectWith(sbv2 *SparseBitVector) (bool, int) {
+	return sbv.intersectWith(sbv2, nil)
+}
+
+// IntersectWithFunc is like IntersectWith, but also calls fn with each bit removed.
+func (sbv *SparseBitVector) IntersectWithFunc(sbv2 *SparseBitVector, fn func(KeyType)) (bool, int) {
+	return sbv.intersectWith(sbv2, fn)
+}
+
+func (sbv *SparseBitVector) intersectWith(sbv2 *SparseBitVector, fn func(KeyType)) (bool, int) {
+	sbv.pageAll()
+	defer sbv.enforce()
+	c2 := sbv2.cursor()
+	removed := sbv.filterArray(func(index KeyType, vec *FiniteBitVector) {
+		if c2.seek(index); !c2.valid || c2.index != index {
+			vec.Clear()
+		} else {
+			vec.IntersectWith(c2.vec())
 		}
+	}, fn)
+	c2 = sbv2.cursor()
+	for e1 := sbv.start; e1 != nil; e1 = e1.next {
 		// skip sbv2 elements not in sbv
-		for e2 != nil && e1 != nil && e2.index < e1.index {
-			e2 = e2.next
-		}
-		// same index
-		if e1 != nil && e2 != nil && e1.index == e2.index {
-			before := e1.Count()
-			e1.IntersectWith(&e2.FiniteBitVector)
-			sbv.count += e1.Count() - before
-			e1 = e1.next
-			e2 = e2.next
+		c2.seek(e1.index)
+		before := e1.FiniteBitVector
+		if c2.valid && c2.index == e1.index {
+			e1.IntersectWith(c2.vec())
+		} else {
+			// remove sbv elements not in sbv2
+			e1.Clear()
 		}
+		removed += before.Count() - e1.Count()
+		sbv.rehash(e1.index, &before, &e1.FiniteBitVector)
+		report(e1.index, &before, &e1.FiniteBitVector, fn)
+		sbv.fit(e1)
 	}
+	sbv.count -= removed
+	return removed != 0, removed
 }
 
 // IntersectWithComplement sets sbv to the intersection of itself and the inverse of sbv2.
-func (sbv *SparseBitVector) IntersectWithComplement(sbv2 *SparseBitVector) {
-	for e1, e2 := sbv.start, sbv2.start; e1 != nil; {
-		// skip sbv elements not in sbv2
-		for e1 != nil && (e2 == nil || e1.index < e2.index) {
-			e1 = e1.next
+// It returns true iff sbv changed, along with the number of bits removed.
+func (sbv *SparseBitVector) IntersectWithComplement(sbv2 *SparseBitVector) (bool, int) {
+	return sbv.intersectWithComplement(sbv2, nil)
+}
+
+// IntersectWithComplementFunc is like IntersectWithComplement, but also calls fn with each bit removed.
+func (sbv *SparseBitVector) IntersectWithComplementFunc(sbv2 *SparseBitVector, fn func(KeyType)) (bool, int) {
+	return sbv.intersectWithComplement(sbv2, fn)
+}
+
+func (sbv *SparseBitVector) intersectWithComplement(sbv2 *SparseBitVector, fn func(KeyType)) (bool, int) {
+	sbv.pageAll()
+	defer sbv.enforce()
+	c2 := sbv2.cursor()
+	removed := sbv.filterArray(func(index KeyType, vec *FiniteBitVector) {
+		if c2.seek(index); c2.valid && c2.index == index {
+			vec.IntersectWithComplement(c2.vec())
 		}
+	}, fn)
+	c2 = sbv2.cursor()
+	for e1 := sbv.start; e1 != nil && c2.valid; {
+		// skip sbv elements not in sbv2
+		e1 = sbv.seek(e1, c2.index)
 		// skip sbv2 elements not in sbv
-		for e2 != nil && e1 != nil && e2.index < e1.index {
-			e2 = e2.next
+		if e1 != nil {
+			c2.seek(e1.index)
 		}
 		// same index
-		if e1 != nil && e2 != nil && e1.index == e2.index {
-			before := e1.Count()
-			e1.IntersectWithComplement(&e2.FiniteBitVector)
-			sbv.count += e1.Count() - before
+		if e1 != nil && c2.valid && e1.index == c2.index {
+			before := e1.FiniteBitVector
+			e1.IntersectWithComplement(c2.vec())
+			removed += before.Count() - e1.Count()
+			sbv.rehash(e1.index, &before, &e1.FiniteBitVector)
+			report(e1.index, &before, &e1.FiniteBitVector, fn)
+			sbv.fit(e1)
 			e1 = e1.next
-			e2 = e2.next
+			c2.next()
 		}
 	}
+	sbv.count -= removed
+	return removed != 0, removed
+}
+
+// SymmetricDifferenceWith sets sbv to the bits which are true in exactly one of itself and sbv2.
+// It returns true iff sbv changed.
+func (sbv *SparseBitVector) SymmetricDifferenceWith(sbv2 *SparseBitVector) bool {
+	if sbv == sbv2 {
+		changed, _ := sbv.Clear()
+		return changed
+	}
+	sbv.pageAll()
+	defer sbv.enforce()
+	e1 := sbv.start
+	for c2 := sbv2.cursor(); c2.valid; c2.next() {
+		// sbv catch-up
+		e1 = sbv.seek(e1, c2.index)
+		found := e1 != nil && e1.index == c2.index
+		before := FiniteBitVector{}
+		if found {
+			before = e1.FiniteBitVector
+		} else {
+			before = sbv.arrayBlock(c2.index)
+		}
+		after := before
+		for w, word := range c2.vec() {
+			after[w] ^= word
+		}
+		sbv.count += after.Count() - before.Count()
+		sbv.rehash(c2.index, &before, &after)
+
+		if found {
+			next := e1.next
+			e1.FiniteBitVector = after
+			sbv.fit(e1)
+			e1 = next
+		} else if e := sbv.storeBlock(c2.index, &after); e != nil {
+			e1 = e.next
+		}
+	}
+	return sbv2.count != 0
+}
+
+// Blocks calls fn with the index and bits of each non-empty block in ascending order.
+// Block index i holds the keys from i*ElementSize up to (i+1)*ElementSize-1.
+func (sbv *SparseBitVector) Blocks(fn func(index KeyType, vec FiniteBitVector)) {
+	sbv.blocks(func(index KeyType, vec *FiniteBitVector) {
+		fn(index, *vec)
+	})
 }
 
 // Iterate returns a channel which publishes all true bits in ascending order.
@@ -237,11 +491,11 @@ func (sbv *SparseBitVector) IntersectWithComplement(sbv2 *SparseBitVector) {
 func (sbv *SparseBitVector) Iterate() <-chan KeyType {
 	c := make(chan KeyType)
 	go func(c chan<- KeyType) {
-		for e := sbv.start; e != nil; e = e.next {
-			for i := e.FindNext(0); i != -1; i = e.FindNext(i + 1) {
-				c <- e.index*ElementSize + KeyType(i)
+		sbv.blocks(func(index KeyType, vec *FiniteBitVector) {
+			for i := vec.FindNext(0); i != -1; i = vec.FindNext(i + 1) {
+				c <- index*ElementSize + KeyType(i)
 			}
-		}
+		})
 		close(c)
 	}(c)
 	return c
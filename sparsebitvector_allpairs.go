@@ -0,0 +1,172 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Pair identifies two vectors by their positions, with I < J, and their Jaccard similarity.
+type Pair struct {
+	I, J    int
+	Jaccard float64
+}
+
+// posting records that a token occurs at position in the prefix of the record with a given rank.
+type posting struct {
+	record, position int
+}
+
+// ceil rounds x up, allowing for rounding error so that filters stay conservative.
+func ceil(x float64) int {
+	return int(math.Ceil(x - 1e-9))
+}
+
+// AllPairs is AllPairsParallel with one worker per available CPU.
+func AllPairs(vecs []*SparseBitVector, t float64) []Pair {
+	return AllPairsParallel(vecs, t, runtime.GOMAXPROCS(0))
+}
+
+// AllPairsParallel returns every pair of vecs with a Jaccard similarity of at least t,
+// ordered by I and then J, using the given number of goroutines.
+// Candidates are found with PPJoin's prefix, length and positional filters
+// over members ordered by ascending frequency, and verified exactly with an
+// intersection which stops once the threshold is out of reach.
+// The goroutines read vecs concurrently, which must not be modified meanwhile.
+func AllPairsParallel(vecs []*SparseBitVector, t float64, workers int) []Pair {
+	if t <= 0 || t > 1 {
+		panic("similarity threshold out of range")
+	}
+	if workers < 1 {
+		panic("worker count must be positive")
+	}
+
+	tokens := make([][]KeyType, len(vecs))
+	frequency := map[KeyType]int{}
+	empty := []int{}
+	for i, vec := range vecs {
+		vec.blocks(func(index KeyType, block *FiniteBitVector) {
+			for b := block.FindNext(0); b != -1; b = block.FindNext(b + 1) {
+				tokens[i] = append(tokens[i], index*ElementSize+KeyType(b))
+			}
+		})
+		for _, token := range tokens[i] {
+			frequency[token]++
+		}
+		if len(tokens[i]) == 0 {
+			empty = append(empty, i)
+		}
+	}
+
+	// rank records by size, and order each record's tokens by ascending frequency
+	// so that prefixes hold rare tokens
+	order := make([]int, len(vecs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return len(tokens[order[a]]) < len(tokens[order[b]]) })
+	for _, x := range tokens {
+		sort.Slice(x, func(a, b int) bool {
+			if frequency[x[a]] != frequency[x[b]] {
+				return frequency[x[a]] < frequency[x[b]]
+			}
+			return x[a] < x[b]
+		})
+	}
+	prefix := func(n int) int {
+		return n - ceil(t*float64(n)) + 1
+	}
+
+	// index the prefix of every record; postings are in ascending rank
+	index := map[KeyType][]posting{}
+	for r, i := range order {
+		x := tokens[i]
+		for position := 0; position < prefix(len(x)) && position < len(x); position++ {
+			index[x[position]] = append(index[x[position]], posting{r, position})
+		}
+	}
+
+	results := make([][]Pair, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := w; r < len(order); r += workers {
+				results[w] = allPairsProbe(results[w], r, order, tokens, vecs, index, t, prefix)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	result := []Pair{}
+	for _, pairs := range results {
+		result = append(result, pairs...)
+	}
+	// empty vectors are identical, and share no tokens to be found by
+	for a := range empty {
+		for _, j := range empty[a+1:] {
+			result = append(result, Pair{I: empty[a], J: j, Jaccard: 1})
+		}
+	}
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].I != result[b].I {
+			return result[a].I < result[b].I
+		}
+		return result[a].J < result[b].J
+	})
+	return result
+}
+
+// allPairsProbe appends the pairs between the record of rank r and records of lower rank.
+func allPairsProbe(pairs []Pair, r int, order []int, tokens [][]KeyType, vecs []*SparseBitVector, index map[KeyType][]posting, t float64, prefix func(int) int) []Pair {
+	x := tokens[order[r]]
+	n := len(x)
+	// overlap counts shared prefix tokens per candidate rank, or is -1 once pruned
+	overlap := map[int]int{}
+	for i := 0; i < prefix(n) && i < n; i++ {
+		for _, p := range index[x[i]] {
+			if p.record >= r {
+				break
+			}
+			m := len(tokens[order[p.record]])
+			if m < ceil(t*float64(n)) || overlap[p.record] < 0 {
+				continue
+			}
+			need := ceil(t / (1 + t) * float64(n+m))
+			rest := n - i - 1
+			if m-p.position-1 < rest {
+				rest = m - p.position - 1
+			}
+			if overlap[p.record]+1+rest < need {
+				overlap[p.record] = -1
+				continue
+			}
+			overlap[p.record]++
+		}
+	}
+
+	for c, shared := range overlap {
+		if shared <= 0 {
+			continue
+		}
+		a, b := order[r], order[c]
+		m := len(tokens[b])
+		union, intersection, ok := vecs[a].unionAndIntersectionSize(vecs[b], ceil(t/(1+t)*float64(n+m)))
+		if !ok {
+			continue
+		}
+		if j := float64(intersection) / float64(union); j >= t {
+			if b < a {
+				a, b = b, a
+			}
+			pairs = append(pairs, Pair{I: a, J: b, Jaccard: j})
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,73 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestAllPairs(t *testing.T) {
+	vecs := []*SparseBitVector{
+		New(1, 2, 3, 4),
+		New(),
+		New(1, 2, 3, 4, 5),
+		NewAdaptive(4, 1, 2, 3, 1000),
+		New(),
+		New(1000, 1<<40),
+	}
+	expected := []Pair{{0, 2, 0.8}, {0, 3, 0.6}, {1, 4, 1}}
+	if pairs := AllPairs(vecs, 0.6); !reflect.DeepEqual(pairs, expected) {
+		t.Error("incorrect pairs", pairs)
+	}
+	if pairs := AllPairs(vecs, 1); !reflect.DeepEqual(pairs, []Pair{{1, 4, 1}}) {
+		t.Error("incorrect identical pairs", pairs)
+	}
+}
+
+func TestAllPairsExhaustive(t *testing.T) {
+	// compare against every pair of clustered random vectors
+	r := rand.New(rand.NewSource(1))
+	vecs := []*SparseBitVector{}
+	for i := 0; i < 200; i++ {
+		base := KeyType(r.Intn(5)) * 1000
+		vec := New()
+		for n := 1 + r.Intn(30); n > 0; n-- {
+			vec.Set(base + KeyType(r.Intn(40)))
+		}
+		vecs = append(vecs, vec)
+	}
+
+	for _, threshold := range []float64{0.2, 0.5, 0.75, 1} {
+		expected := []Pair{}
+		for i := range vecs {
+			for j := i + 1; j < len(vecs); j++ {
+				if s := vecs[i].Jaccard(vecs[j]); s >= threshold {
+					expected = append(expected, Pair{i, j, s})
+				}
+			}
+		}
+		for _, workers := range []int{1, 3} {
+			if pairs := AllPairsParallel(vecs, threshold, workers); !reflect.DeepEqual(pairs, expected) {
+				t.Error("incorrect pairs", threshold, workers, len(pairs), len(expected))
+			}
+		}
+	}
+}
+
+func TestUnionAndIntersectionSizeBound(t *testing.T) {
+	a, b := New(1, 2, 3, 1000, 2000), New(2, 3, 2000, 3000)
+	if u, i, ok := a.unionAndIntersectionSize(b, 3); !ok || u != 6 || i != 3 {
+		t.Error("incorrect intersection", u, i, ok)
+	}
+	if _, _, ok := a.unionAndIntersectionSize(b, 4); ok {
+		t.Error("unexpected intersection")
+	}
+	// the walk stops before reaching the later elements
+	if _, i, ok := a.unionAndIntersectionSize(New(0, 1000, 2000, 3000), 4); ok || i != 0 {
+		t.Error("incorrect early termination", i, ok)
+	}
+}
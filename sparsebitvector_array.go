@@ -0,0 +1,288 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"sort"
+	"unsafe"
+)
+
+// arrayspan is the number of keys covered by one array container.
+const arrayspan = 1 << 16
+
+// spanblocks is the number of blocks covered by one array container.
+const spanblocks = arrayspan / ElementSize
+
+// arrayContainer holds the array keys from high*arrayspan up to
+// (high+1)*arrayspan-1 as ascending offsets, so that inserting a key
+// only moves the keys of its own container.
+type arrayContainer struct {
+	high KeyType
+	low  []uint16
+}
+
+// NewAdaptive creates a SparseBitVector which stores blocks with fewer than
+// threshold true bits as 16-bit offsets in sorted array containers rather than as elements.
+// Blocks are promoted to a FiniteBitVector once they reach the threshold.
+func NewAdaptive(threshold int, set ...KeyType) *SparseBitVector {
+	if threshold < 1 || threshold > ElementSize {
+		panic("threshold out of range for element")
+	}
+	result := &SparseBitVector{threshold: threshold}
+	for _, i := range set {
+		result.Set(i)
+	}
+	return result
+}
+
+// container returns the position of the first array container with a high of at least high.
+func (sbv *SparseBitVector) container(high KeyType) int {
+	return sort.Search(len(sbv.arrays), func(i int) bool { return sbv.arrays[i].high >= high })
+}
+
+// arrayRange returns the array container holding block index, if any,
+// and the bounds of the block's offsets within it.
+func (sbv *SparseBitVector) arrayRange(index KeyType) (*arrayContainer, int, int) {
+	i := sbv.container(index / spanblocks)
+	if i == len(sbv.arrays) || sbv.arrays[i].high != index/spanblocks {
+		return nil, 0, 0
+	}
+	c := &sbv.arrays[i]
+	first := uint16(index % spanblocks * ElementSize)
+	lo := sort.Search(len(c.low), func(j int) bool { return c.low[j] >= first })
+	hi := lo
+	for hi < len(c.low) && c.low[hi]/ElementSize == first/ElementSize {
+		hi++
+	}
+	return c, lo, hi
+}
+
+// arrayBlock returns the bits of block index held in the array.
+func (sbv *SparseBitVector) arrayBlock(index KeyType) FiniteBitVector {
+	block := FiniteBitVector{}
+	if c, lo, hi := sbv.arrayRange(index); c != nil {
+		for _, low := range c.low[lo:hi] {
+			block.Set(uint(low % ElementSize))
+		}
+	}
+	return block
+}
+
+func (sbv *SparseBitVector) testArray(key KeyType) bool {
+	i := sbv.container(key / arrayspan)
+	if i == len(sbv.arrays) || sbv.arrays[i].high != key/arrayspan {
+		return false
+	}
+	c, low := &sbv.arrays[i], uint16(key%arrayspan)
+	j := sort.Search(len(c.low), func(j int) bool { return c.low[j] >= low })
+	return j < len(c.low) && c.low[j] == low
+}
+
+// nextArray returns the first array key at or after key, if any.
+func (sbv *SparseBitVector) nextArray(key KeyType) (KeyType, bool) {
+	high, low := key/arrayspan, uint16(key%arrayspan)
+	for i := sbv.container(high); i < len(sbv.arrays); i++ {
+		c := &sbv.arrays[i]
+		j := 0
+		if c.high == high {
+			j = sort.Search(len(c.low), func(j int) bool { return c.low[j] >= low })
+		}
+		if j < len(c.low) {
+			return c.high*arrayspan + KeyType(c.low[j]), true
+		}
+	}
+	return 0, false
+}
+
+// prevArray returns the last array key at or before key, if any.
+func (sbv *SparseBitVector) prevArray(key KeyType) (KeyType, bool) {
+	high, low := key/arrayspan, uint16(key%arrayspan)
+	for i := sbv.container(high+1) - 1; i >= 0; i-- {
+		c := &sbv.arrays[i]
+		j := len(c.low)
+		if c.high == high {
+			j = sort.Search(len(c.low), func(j int) bool { return c.low[j] > low })
+		}
+		if j > 0 {
+			return c.high*arrayspan + KeyType(c.low[j-1]), true
+		}
+	}
+	return 0, false
+}
+
+func (sbv *SparseBitVector) setArray(key KeyType) bool {
+	high, low := key/arrayspan, uint16(key%arrayspan)
+	i := sbv.container(high)
+	if i == len(sbv.arrays) || sbv.arrays[i].high != high {
+		sbv.arrays = append(sbv.arrays, arrayContainer{})
+		copy(sbv.arrays[i+1:], sbv.arrays[i:])
+		sbv.arrays[i] = arrayContainer{high: high}
+	}
+	c := &sbv.arrays[i]
+	j := sort.Search(len(c.low), func(j int) bool { return c.low[j] >= low })
+	if j < len(c.low) && c.low[j] == low {
+		return false
+	}
+	c.low = append(c.low, 0)
+	copy(c.low[j+1:], c.low[j:])
+	c.low[j] = low
+	sbv.count++
+
+	if _, lo, hi := sbv.arrayRange(key / ElementSize); hi-lo >= sbv.threshold {
+		sbv.promote(key / ElementSize)
+	}
+	return true
+}
+
+func (sbv *SparseBitVector) unsetArray(key KeyType) bool {
+	high, low := key/arrayspan, uint16(key%arrayspan)
+	i := sbv.container(high)
+	if i == len(sbv.arrays) || sbv.arrays[i].high != high {
+		return false
+	}
+	c := &sbv.arrays[i]
+	j := sort.Search(len(c.low), func(j int) bool { return c.low[j] >= low })
+	if j == len(c.low) || c.low[j] != low {
+		return false
+	}
+	c.low = append(c.low[:j], c.low[j+1:]...)
+	if len(c.low) == 0 {
+		sbv.arrays = append(sbv.arrays[:i], sbv.arrays[i+1:]...)
+	}
+	sbv.count--
+	return true
+}
+
+// storeArray replaces the array keys of block index with the true bits of vec.
+// It does not update sbv's count or hash.
+func (sbv *SparseBitVector) storeArray(index KeyType, vec *FiniteBitVector) {
+	high := index / spanblocks
+	i := sbv.container(high)
+	if i == len(sbv.arrays) || sbv.arrays[i].high != high {
+		if vec.Count() == 0 {
+			return
+		}
+		sbv.arrays = append(sbv.arrays, arrayContainer{})
+		copy(sbv.arrays[i+1:], sbv.arrays[i:])
+		sbv.arrays[i] = arrayContainer{high: high}
+	}
+
+	keys := make([]uint16, 0, vec.Count())
+	base := uint16(index % spanblocks * ElementSize)
+	for b := vec.FindNext(0); b != -1; b = vec.FindNext(b + 1) {
+		keys = append(keys, base+uint16(b))
+	}
+	c, lo, hi := sbv.arrayRange(index)
+	c.low = append(c.low[:lo], append(keys, c.low[hi:]...)...)
+	if len(c.low) == 0 {
+		sbv.arrays = append(sbv.arrays[:i], sbv.arrays[i+1:]...)
+	}
+}
+
+// storeBlock sets block index, which has no element, to vec.
+// Blocks below the threshold are kept in the array; otherwise the
+// bits are moved into a new element, which is returned.
+// It does not update sbv's count or hash.
+func (sbv *SparseBitVector) storeBlock(index KeyType, vec *FiniteBitVector) *element {
+	if vec.Count() == 0 || vec.Count() < sbv.threshold {
+		sbv.storeArray(index, vec)
+		return nil
+	}
+	if sbv.threshold > 0 {
+		sbv.storeArray(index, &FiniteBitVector{})
+	}
+	e := sbv.insert(index)
+	e.FiniteBitVector = *vec
+	return e
+}
+
+// promote moves the array keys of block index into an element.
+func (sbv *SparseBitVector) promote(index KeyType) {
+	block := sbv.arrayBlock(index)
+	sbv.storeBlock(index, &block)
+}
+
+// demote moves an element's true bits into the array and deletes it.
+func (sbv *SparseBitVector) demote(e *element) {
+	sbv.storeArray(e.index, &e.FiniteBitVector)
+	sbv.delete(e)
+}
+
+// fit deletes e if it is empty, or demotes it if it is below the threshold.
+func (sbv *SparseBitVector) fit(e *element) {
+	if e.Count() == 0 {
+		sbv.delete(e)
+	} else if e.Count() < sbv.threshold {
+		sbv.demote(e)
+	}
+}
+
+// filterArray calls op with the index and bits of each array block in ascending order,
+// and keeps only the bits op leaves set. op must not set bits or modify sbv.
+// It returns the number of bits removed, and updates sbv's hash but not its count.
+func (sbv *SparseBitVector) filterArray(op func(index KeyType, vec *FiniteBitVector), fn func(KeyType)) int {
+	removed := 0
+	arrays := sbv.arrays[:0]
+	for _, c := range sbv.arrays {
+		low := c.low[:0]
+		for i := 0; i < len(c.low); {
+			index := c.high*spanblocks + KeyType(c.low[i]/ElementSize)
+			before := FiniteBitVector{}
+			j := i
+			for ; j < len(c.low) && c.low[j]/ElementSize == c.low[i]/ElementSize; j++ {
+				before.Set(uint(c.low[j] % ElementSize))
+			}
+			after := before
+			op(index, &after)
+			removed += before.Count() - after.Count()
+			sbv.rehash(index, &before, &after)
+			report(index, &before, &after, fn)
+			for ; i < j; i++ {
+				if after.Test(uint(c.low[i] % ElementSize)) {
+					low = append(low, c.low[i])
+				}
+			}
+		}
+		if len(low) > 0 {
+			c.low = low
+			arrays = append(arrays, c)
+		}
+	}
+	sbv.arrays = arrays
+	return removed
+}
+
+// equalArrays returns true iff a and b hold the same array keys.
+func equalArrays(a, b []arrayContainer) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].high != b[i].high || len(a[i].low) != len(b[i].low) {
+			return false
+		}
+		for j := range a[i].low {
+			if a[i].low[j] != b[i].low[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// arrayStats returns the number of array keys and blocks, and the bytes they hold.
+func (sbv *SparseBitVector) arrayStats() (keys, blocks, bytes int) {
+	bytes = cap(sbv.arrays) * int(unsafe.Sizeof(arrayContainer{}))
+	for _, c := range sbv.arrays {
+		keys += len(c.low)
+		bytes += cap(c.low) * int(unsafe.Sizeof(uint16(0)))
+		for j, low := range c.low {
+			if j == 0 || low/ElementSize != c.low[j-1]/ElementSize {
+				blocks++
+			}
+		}
+	}
+	return keys, blocks, bytes
+}
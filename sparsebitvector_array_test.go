@@ -0,0 +1,165 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestAdaptiveOperation(t *testing.T) {
+	vec := NewAdaptive(3, 0, 1000, 1000000)
+	if s := vec.Stats(); s.Elements != 0 || s.ArrayBlocks != 3 || s.ArrayKeys != 3 {
+		t.Error("unexpected stats", s)
+	}
+	if !vec.Test(1000) || vec.Test(1001) || vec.Count() != 3 {
+		t.Error("incorrect contents", vec)
+	}
+
+	vec.Set(1)
+	vec.Set(1)
+	if s := vec.Stats(); s.Elements != 0 || s.ArrayKeys != 4 {
+		t.Error("unexpected stats", s)
+	}
+
+	// third bit in block 0 promotes it
+	vec.Set(2)
+	if s := vec.Stats(); s.Elements != 1 || s.ArrayBlocks != 2 || s.ArrayKeys != 2 {
+		t.Error("unexpected stats", s)
+	}
+	if vec.String() != "[0 1 2 1000 1000000]" || vec.Count() != 5 {
+		t.Error("incorrect contents", vec)
+	}
+
+	// dropping below the threshold demotes it
+	vec.Unset(1)
+	if s := vec.Stats(); s.Elements != 0 || s.ArrayKeys != 4 {
+		t.Error("unexpected stats", s)
+	}
+	if vec.String() != "[0 2 1000 1000000]" || vec.Count() != 4 {
+		t.Error("incorrect contents", vec)
+	}
+
+	vec.Unset(1000)
+	vec.Unset(1000)
+	if vec.Test(1000) || vec.Count() != 3 {
+		t.Error("incorrect contents", vec)
+	}
+
+	vec.Clear()
+	if s := vec.Stats(); vec.Count() != 0 || s.ArrayKeys != 0 {
+		t.Error("not empty", vec)
+	}
+}
+
+func TestAdaptiveBinaryOperations(t *testing.T) {
+	vec1 := NewAdaptive(2, 0, 1, 63, 1000000)
+	vec2 := New(0, 127, 128, 1000000)
+
+	if !vec1.Equals(NewAdaptive(4, 0, 1, 63, 1000000)) || !vec1.Equals(New(0, 1, 63, 1000000)) {
+		t.Error("vec1 should equal its copies", vec1)
+	}
+	if !vec1.Contains(NewAdaptive(2, 1, 1000000)) || vec1.Contains(vec2) {
+		t.Error("incorrect containment", vec1, vec2)
+	}
+	if u, i := vec1.UnionAndIntersectionSize(vec2); u != 6 || i != 2 {
+		t.Error("incorrect union or intersection size", u, i, vec1, vec2)
+	}
+
+	if vec1.UnionWith(vec2); vec1.String() != "[0 1 63 127 128 1000000]" {
+		t.Error("incorrect union", vec1)
+	}
+	if s := vec1.Stats(); s.Elements != 1 || s.ArrayBlocks != 2 {
+		t.Error("unexpected stats", s)
+	}
+
+	if vec1.IntersectWithComplement(New(1, 63)); vec1.String() != "[0 127 128 1000000]" {
+		t.Error("incorrect intersection", vec1)
+	}
+	if vec1.IntersectWith(New(0, 128, 1000000)); vec1.String() != "[0 128 1000000]" {
+		t.Error("incorrect intersection", vec1)
+	}
+	if s := vec1.Stats(); s.Elements != 0 || s.ArrayKeys != 3 || vec1.Count() != 3 {
+		t.Error("unexpected stats", s)
+	}
+}
+
+func TestArrayReadOnly(t *testing.T) {
+	vec1 := NewAdaptive(4, 0, 1, 1000, 1000000)
+	vec2 := New(0, 1000, 5000)
+	array := vec1.arrays[0].low
+
+	vec1.Equals(vec2)
+	vec2.Contains(vec1)
+	vec1.UnionAndIntersectionSize(vec2)
+	Cmp(vec1, vec2)
+	UnionAll(vec1, vec2)
+	IntersectAll(vec1, vec2)
+	New().SetToUnion(vec1, vec2)
+	if vec2.UnionWith(vec1); vec2.String() != "[0 1 1000 5000 1000000]" {
+		t.Error("incorrect union", vec2)
+	}
+	if vec1.start != nil || len(vec1.arrays) != 2 || &vec1.arrays[0].low[0] != &array[0] {
+		t.Error("read-only operand was modified", vec1.Stats())
+	}
+}
+
+func TestAdaptiveContainers(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	vec1, vec2 := NewAdaptive(8), NewAdaptive(8)
+	plain1, plain2 := New(), New()
+	for i := 0; i < 5000; i++ {
+		key := KeyType(r.Intn(4 * arrayspan))
+		vec1.Set(key)
+		plain1.Set(key)
+		key = KeyType(r.Intn(4 * arrayspan))
+		vec2.Set(key)
+		plain2.Set(key)
+	}
+	if !vec1.Equals(plain1) || vec1.Hash() != plain1.Hash() || len(vec1.arrays) != 4 {
+		t.Error("incorrect contents", vec1.Stats())
+	}
+	k1, _ := vec1.nextArray(arrayspan)
+	k2, _ := plain1.NextSet(arrayspan)
+	if p1, _ := vec1.prevArray(arrayspan); k1 != k2 || p1 >= arrayspan {
+		t.Error("incorrect array search", k1, k2, p1)
+	}
+
+	vec1.UnionWith(vec2)
+	plain1.UnionWith(plain2)
+	if !vec1.Equals(plain1) || vec1.Hash() != plain1.Hash() || !adaptive(vec1) {
+		t.Error("incorrect union", vec1.Stats())
+	}
+	vec1.IntersectWithComplement(NewRange(0, arrayspan))
+	plain1.IntersectWithComplement(NewRange(0, arrayspan))
+	if !vec1.Equals(plain1) || vec1.Hash() != plain1.Hash() || len(vec1.arrays) != 3 || !adaptive(vec1) {
+		t.Error("incorrect difference", vec1.Stats())
+	}
+	vec1.IntersectWith(vec2)
+	plain1.IntersectWith(plain2)
+	if !vec1.Equals(plain1) || vec1.Hash() != plain1.Hash() || vec1.Count() != plain1.Count() || !adaptive(vec1) {
+		t.Error("incorrect intersection", vec1.Stats())
+	}
+	vec1.SetToUnion(vec2, NewRange(0, 1000))
+	plain1.SetToUnion(plain2, NewRange(0, 1000))
+	if !vec1.Equals(plain1) || vec1.Hash() != plain1.Hash() || !adaptive(vec1) {
+		t.Error("incorrect assignment", vec1.Stats())
+	}
+}
+
+// adaptive returns true iff each block of sbv below its threshold is in the array.
+func adaptive(sbv *SparseBitVector) bool {
+	for e := sbv.start; e != nil; e = e.next {
+		if e.Count() < sbv.threshold {
+			return false
+		}
+	}
+	for c := sbv.cursor(); c.valid; c.next() {
+		if c.inArray && c.vec().Count() >= sbv.threshold {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// The binary block format is a little-endian uint64 block count followed by
+// that many blocks, in ascending index order, each encoded as the uint64
+// element index and the FiniteBitVector's words.
+
+// blockbytes is the encoded size of a single block.
+const blockbytes = 8 * (1 + wordsperelement)
+
+func encodeBlock(buf []byte, index KeyType, vec *FiniteBitVector) {
+	binary.LittleEndian.PutUint64(buf, uint64(index))
+	for w := range vec {
+		binary.LittleEndian.PutUint64(buf[8*(w+1):], uint64(vec[w]))
+	}
+}
+
+func decodeBlock(buf []byte) (KeyType, FiniteBitVector) {
+	vec := FiniteBitVector{}
+	for w := range vec {
+		vec[w] = elementwordtype(binary.LittleEndian.Uint64(buf[8*(w+1):]))
+	}
+	return KeyType(binary.LittleEndian.Uint64(buf)), vec
+}
+
+// WriteTo writes sbv to w in the binary block format.
+func (sbv *SparseBitVector) WriteTo(w io.Writer) (int64, error) {
+	buf := make([]byte, blockbytes)
+	binary.LittleEndian.PutUint64(buf, uint64(sbv.blockCount()))
+	n, err := w.Write(buf[:8])
+	written := int64(n)
+
+	sbv.blocks(func(index KeyType, vec *FiniteBitVector) {
+		if err == nil {
+			encodeBlock(buf, index, vec)
+			n, err = w.Write(buf)
+			written += int64(n)
+		}
+	})
+	return written, err
+}
+
+// ReadFrom replaces the contents of sbv with a vector read from r in the binary block format.
+func (sbv *SparseBitVector) ReadFrom(r io.Reader) (int64, error) {
+	sbv.Clear()
+	buf := make([]byte, blockbytes)
+	n, err := io.ReadFull(r, buf[:8])
+	read := int64(n)
+	if err != nil {
+		return read, err
+	}
+
+	var last KeyType
+	for blocks, first := binary.LittleEndian.Uint64(buf), true; blocks > 0; blocks, first = blocks-1, false {
+		n, err = io.ReadFull(r, buf)
+		read += int64(n)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			sbv.enforce()
+			return read, err
+		}
+
+		index, vec := decodeBlock(buf)
+		if !first && index <= last {
+			sbv.enforce()
+			return read, errors.New("sparsebitvector: blocks out of order")
+		}
+		last = index
+		sbv.storeBlock(index, &vec)
+		sbv.count += vec.Count()
+		sbv.rehash(index, &FiniteBitVector{}, &vec)
+		sbv.enforce()
+	}
+	return read, nil
+}
+
+// MarshalBinary encodes sbv in the binary block format.
+func (sbv *SparseBitVector) MarshalBinary() ([]byte, error) {
+	buf := bytes.Buffer{}
+	_, err := sbv.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary replaces the contents of sbv with data in the binary block format.
+func (sbv *SparseBitVector) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if _, err := sbv.ReadFrom(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return errors.New("sparsebitvector: trailing data")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for _, vec := range []*SparseBitVector{New(), New(0, 5, 127, 128, 1<<40), NewAdaptive(2, 0, 5, 1000, 1<<40)} {
+		data, err := vec.MarshalBinary()
+		if err != nil {
+			t.Error(err)
+		}
+		if len(data) != 8+vec.blockCount()*blockbytes {
+			t.Error("unexpected length", len(data))
+		}
+
+		result := New(1, 2, 3)
+		if err := result.UnmarshalBinary(data); err != nil {
+			t.Error(err)
+		}
+		if !result.Equals(vec) || result.Count() != vec.Count() {
+			t.Error("incorrect round trip", result, vec)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	buf := bytes.Buffer{}
+	vec1 := New(1, 1000)
+	vec2 := New(2, 1<<50)
+	if n, err := vec1.WriteTo(&buf); err != nil || n != 8+2*blockbytes {
+		t.Error("unexpected write", n, err)
+	}
+	if _, err := vec2.WriteTo(&buf); err != nil {
+		t.Error(err)
+	}
+
+	result := New()
+	if n, err := result.ReadFrom(&buf); err != nil || n != 8+2*blockbytes || !result.Equals(vec1) {
+		t.Error("unexpected read", n, err, result)
+	}
+	if _, err := result.ReadFrom(&buf); err != nil || !result.Equals(vec2) {
+		t.Error("unexpected read", err, result)
+	}
+	if _, err := result.ReadFrom(&buf); err != io.EOF {
+		t.Error("expected EOF", err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	data, _ := New(1, 1000).MarshalBinary()
+	vec := New()
+	if err := vec.UnmarshalBinary(data[:len(data)-1]); err != io.ErrUnexpectedEOF {
+		t.Error("expected unexpected EOF", err)
+	}
+	if err := vec.UnmarshalBinary(append(data, 0)); err == nil {
+		t.Error("expected trailing data error")
+	}
+
+	// swap the two blocks
+	swapped := append([]byte{}, data[:8]...)
+	swapped = append(swapped, data[8+blockbytes:]...)
+	swapped = append(swapped, data[8:8+blockbytes]...)
+	if err := vec.UnmarshalBinary(swapped); err == nil {
+		t.Error("expected ordering error")
+	}
+}
@@ -0,0 +1,130 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"container/list"
+	"unsafe"
+)
+
+// Op identifies an operation memoized by an OpCache.
+type Op int
+
+// Operations memoized by an OpCache.
+const (
+	OpUnion        Op = iota // as UnionWith
+	OpIntersection           // as IntersectWith
+	OpDifference             // as IntersectWithComplement
+)
+
+// opKey identifies a cached result by operation, operand identity and operand contents.
+// The hashes detect operands which were modified after the result was cached.
+type opKey struct {
+	op     Op
+	a, b   *SparseBitVector
+	ha, hb uint64
+}
+
+type opEntry struct {
+	key    opKey
+	result *SparseBitVector
+}
+
+// OpCache memoizes the results of set operations on pairs of vectors,
+// like the computed table of a BDD package.
+// It works best with vectors from an Interner, whose identity implies their contents.
+// The least recently used results are evicted once the cache reaches its size limit.
+type OpCache struct {
+	limit   int
+	entries map[opKey]*list.Element
+	lru     *list.List // of *opEntry, most recently used first
+	hits    int
+	misses  int
+}
+
+// NewOpCache creates an OpCache holding at most limit results.
+func NewOpCache(limit int) *OpCache {
+	if limit < 1 {
+		panic("cache limit must be positive")
+	}
+	return &OpCache{limit: limit, entries: make(map[opKey]*list.Element), lru: list.New()}
+}
+
+// Union returns the union of a and b.
+// The result is shared by the cache and must not be modified.
+func (c *OpCache) Union(a, b *SparseBitVector) *SparseBitVector {
+	return c.apply(OpUnion, a, b)
+}
+
+// Intersection returns the intersection of a and b.
+// The result is shared by the cache and must not be modified.
+func (c *OpCache) Intersection(a, b *SparseBitVector) *SparseBitVector {
+	return c.apply(OpIntersection, a, b)
+}
+
+// Difference returns the intersection of a and the inverse of b.
+// The result is shared by the cache and must not be modified.
+func (c *OpCache) Difference(a, b *SparseBitVector) *SparseBitVector {
+	return c.apply(OpDifference, a, b)
+}
+
+// apply returns the cached result of op, computing it on a miss.
+func (c *OpCache) apply(op Op, a, b *SparseBitVector) *SparseBitVector {
+	key := opKey{op: op, a: a, b: b, ha: a.Hash(), hb: b.Hash()}
+	if op != OpDifference && (key.ha > key.hb || key.ha == key.hb && uintptr(unsafe.Pointer(a)) > uintptr(unsafe.Pointer(b))) {
+		// commutative operations share an entry for both operand orders,
+		// which are ordered by hash and then by identity
+		key.a, key.b, key.ha, key.hb = b, a, key.hb, key.ha
+	}
+	if node, ok := c.entries[key]; ok {
+		c.hits++
+		c.lru.MoveToFront(node)
+		return node.Value.(*opEntry).result
+	}
+	c.misses++
+
+	result := a.clone()
+	switch op {
+	case OpUnion:
+		result.UnionWith(b)
+	case OpIntersection:
+		result.IntersectWith(b)
+	case OpDifference:
+		result.IntersectWithComplement(b)
+	default:
+		panic("unknown operation")
+	}
+
+	c.entries[key] = c.lru.PushFront(&opEntry{key: key, result: result})
+	for c.lru.Len() > c.limit {
+		oldest := c.lru.Back()
+		delete(c.entries, oldest.Value.(*opEntry).key)
+		c.lru.Remove(oldest)
+	}
+	return result
+}
+
+// Len returns the number of cached results.
+func (c *OpCache) Len() int {
+	return c.lru.Len()
+}
+
+// Hits returns the number of lookups answered from the cache.
+func (c *OpCache) Hits() int {
+	return c.hits
+}
+
+// Misses returns the number of lookups which computed a new result.
+func (c *OpCache) Misses() int {
+	return c.misses
+}
+
+// Clear discards all cached results and resets the counters.
+func (c *OpCache) Clear() {
+	c.entries = make(map[opKey]*list.Element)
+	c.lru.Init()
+	c.hits = 0
+	c.misses = 0
+}
@@ -0,0 +1,49 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import "testing"
+
+func TestOpCache(t *testing.T) {
+	c := NewOpCache(2)
+	a, b := New(1, 2, 3), New(3, 4)
+
+	u := c.Union(a, b)
+	if u.String() != "[1 2 3 4]" || c.Hits() != 0 || c.Misses() != 1 {
+		t.Error("incorrect union", u, c.Hits(), c.Misses())
+	}
+	if c.Union(b, a) != u || c.Hits() != 1 {
+		t.Error("commuted union missed", c.Hits())
+	}
+	if d := c.Difference(a, b); d.String() != "[1 2]" || c.Difference(b, a).String() != "[4]" {
+		t.Error("incorrect difference", d)
+	}
+	if c.Len() != 2 || c.Misses() != 3 {
+		t.Error("incorrect size", c.Len(), c.Misses())
+	}
+
+	// the union was least recently used and has been evicted
+	if c.Union(a, b) == u || c.Misses() != 4 {
+		t.Error("stale entry", c.Misses())
+	}
+
+	// modified operands are not answered from the cache
+	i := c.Intersection(a, b)
+	a.Unset(3)
+	if i.String() != "[3]" || c.Intersection(a, b).Count() != 0 {
+		t.Error("incorrect intersection", i)
+	}
+
+	c.Clear()
+	if c.Len() != 0 || c.Hits() != 0 || c.Misses() != 0 {
+		t.Error("incorrect clear", c.Len())
+	}
+
+	// operands with equal hashes also share an entry for both orders
+	e, f := New(5), New(5)
+	if c.Union(e, f) != c.Union(f, e) || c.Hits() != 1 || c.Misses() != 1 {
+		t.Error("commuted union of equal operands missed", c.Hits(), c.Misses())
+	}
+}
@@ -0,0 +1,127 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"container/heap"
+	"math"
+)
+
+// gain is a candidate's marginal gain per unit cost, as of when it was last computed.
+type gain struct {
+	index int
+	ratio float64
+}
+
+// gainHeap orders candidates by descending ratio and then ascending index.
+type gainHeap []gain
+
+func (h gainHeap) Len() int { return len(h) }
+func (h gainHeap) Less(i, j int) bool {
+	if h[i].ratio != h[j].ratio {
+		return h[i].ratio > h[j].ratio
+	}
+	return h[i].index < h[j].index
+}
+func (h gainHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *gainHeap) Push(x interface{}) { *h = append(*h, x.(gain)) }
+func (h *gainHeap) Pop() interface{} {
+	old := *h
+	g := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return g
+}
+
+// SetCover chooses candidates whose union covers universe, greedily taking the
+// candidate which covers the most uncovered bits at each step.
+// It returns the chosen indices and the number of bits each newly covered.
+// If the candidates cannot cover universe, it stops once no candidate covers more.
+func SetCover(universe *SparseBitVector, candidates []*SparseBitVector) ([]int, []int) {
+	return WeightedSetCover(universe, candidates, nil)
+}
+
+// WeightedSetCover is like SetCover, but takes the candidate which covers
+// the most uncovered bits per unit of its cost at each step.
+// Costs must be positive, and nil costs are all 1.
+func WeightedSetCover(universe *SparseBitVector, candidates []*SparseBitVector, costs []float64) ([]int, []int) {
+	return greedyCover(universe.clone(), candidates, costs, len(candidates), math.Inf(1))
+}
+
+// MaxCoverage chooses up to k candidates covering as many bits as possible,
+// greedily taking the candidate which covers the most uncovered bits at each step.
+// It returns the chosen indices and the number of bits each newly covered.
+func MaxCoverage(candidates []*SparseBitVector, k int) ([]int, []int) {
+	return greedyCover(UnionAll(candidates...), candidates, nil, k, math.Inf(1))
+}
+
+// WeightedMaxCoverage chooses candidates with a total cost of at most budget
+// covering as many bits as possible.
+// It greedily takes the affordable candidate covering the most uncovered bits
+// per unit of cost, but returns the best single candidate instead if it covers more.
+// Costs must be positive, and nil costs are all 1.
+func WeightedMaxCoverage(candidates []*SparseBitVector, costs []float64, budget float64) ([]int, []int) {
+	chosen, gains := greedyCover(UnionAll(candidates...), candidates, costs, len(candidates), budget)
+	total := 0
+	for _, g := range gains {
+		total += g
+	}
+	best, bestCount := -1, total
+	for i, candidate := range candidates {
+		if cost(costs, i) <= budget && candidate.Count() > bestCount {
+			best, bestCount = i, candidate.Count()
+		}
+	}
+	if best != -1 {
+		return []int{best}, []int{bestCount}
+	}
+	return chosen, gains
+}
+
+// cost returns the cost of candidate i, which is 1 if costs is nil.
+func cost(costs []float64, i int) float64 {
+	if costs == nil {
+		return 1
+	}
+	return costs[i]
+}
+
+// greedyCover covers bits of uncovered with up to k candidates within budget.
+// Marginal gains only shrink as bits are covered, so each candidate's gain is
+// recomputed lazily, when it reaches the top of the heap with a stale ratio.
+func greedyCover(uncovered *SparseBitVector, candidates []*SparseBitVector, costs []float64, k int, budget float64) ([]int, []int) {
+	h := gainHeap{}
+	for i, candidate := range candidates {
+		if cost(costs, i) <= 0 {
+			panic("cost must be positive")
+		}
+		if cost(costs, i) <= budget {
+			h = append(h, gain{i, float64(candidate.IntersectionSize(uncovered)) / cost(costs, i)})
+		}
+	}
+	heap.Init(&h)
+
+	chosen, gains := []int{}, []int{}
+	for len(h) > 0 && len(chosen) < k && uncovered.Count() > 0 {
+		top := heap.Pop(&h).(gain)
+		if cost(costs, top.index) > budget {
+			continue
+		}
+		n := candidates[top.index].IntersectionSize(uncovered)
+		if n == 0 {
+			continue
+		}
+		top.ratio = float64(n) / cost(costs, top.index)
+		// another candidate may now be better
+		if len(h) > 0 && (gainHeap{h[0], top}).Less(0, 1) {
+			heap.Push(&h, top)
+			continue
+		}
+		chosen = append(chosen, top.index)
+		gains = append(gains, n)
+		budget -= cost(costs, top.index)
+		uncovered.IntersectWithComplement(candidates[top.index])
+	}
+	return chosen, gains
+}
@@ -0,0 +1,73 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetCover(t *testing.T) {
+	universe := New(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	candidates := []*SparseBitVector{
+		New(1, 2, 3, 8, 9, 10),
+		New(1, 2, 3, 4, 5),
+		New(4, 5, 7),
+		New(5, 6, 7),
+		New(6, 7, 8, 9, 10),
+	}
+	// ties are broken by the lower index
+	chosen, gains := SetCover(universe, candidates)
+	if !reflect.DeepEqual(chosen, []int{0, 2, 3}) || !reflect.DeepEqual(gains, []int{6, 3, 1}) {
+		t.Error("incorrect cover", chosen, gains)
+	}
+	if universe.Count() != 10 {
+		t.Error("universe modified", universe)
+	}
+
+	// the expensive large candidate is avoided
+	chosen, gains = WeightedSetCover(universe, candidates, []float64{10, 1, 1, 1, 1})
+	if !reflect.DeepEqual(chosen, []int{1, 4}) || !reflect.DeepEqual(gains, []int{5, 5}) {
+		t.Error("incorrect weighted cover", chosen, gains)
+	}
+
+	// uncoverable bits are left uncovered
+	chosen, gains = SetCover(New(1, 2, 100), candidates)
+	if !reflect.DeepEqual(chosen, []int{0}) || !reflect.DeepEqual(gains, []int{2}) {
+		t.Error("incorrect partial cover", chosen, gains)
+	}
+}
+
+func TestMaxCoverage(t *testing.T) {
+	candidates := []*SparseBitVector{
+		New(1, 2, 3, 8, 9, 10),
+		New(1, 2, 3, 4, 5),
+		New(4, 5, 7),
+		New(5, 6, 7),
+		New(6, 7, 8, 9, 10),
+	}
+	if chosen, gains := MaxCoverage(candidates, 2); !reflect.DeepEqual(chosen, []int{0, 2}) || !reflect.DeepEqual(gains, []int{6, 3}) {
+		t.Error("incorrect coverage", chosen, gains)
+	}
+	if chosen, _ := MaxCoverage(candidates, 0); len(chosen) != 0 {
+		t.Error("incorrect empty coverage", chosen)
+	}
+
+	costs := []float64{4, 3, 1, 1, 3}
+	if chosen, gains := WeightedMaxCoverage(candidates, costs, 5); !reflect.DeepEqual(chosen, []int{2, 0}) || !reflect.DeepEqual(gains, []int{3, 6}) {
+		t.Error("incorrect weighted coverage", chosen, gains)
+	}
+
+	// a single candidate beats the greedy choice of a cheap one
+	costs = []float64{1, 10}
+	if chosen, gains := WeightedMaxCoverage([]*SparseBitVector{New(1), New(1, 2, 3, 4)}, costs, 10); !reflect.DeepEqual(chosen, []int{1}) || !reflect.DeepEqual(gains, []int{4}) {
+		t.Error("incorrect single candidate coverage", chosen, gains)
+	}
+
+	// nil costs are unit costs
+	if chosen, gains := WeightedMaxCoverage(candidates, nil, 2); !reflect.DeepEqual(chosen, []int{0, 2}) || !reflect.DeepEqual(gains, []int{6, 3}) {
+		t.Error("incorrect unit cost coverage", chosen, gains)
+	}
+}
@@ -12,6 +12,21 @@ type element struct {
 	next  *element
 }
 
+// report calls fn with each bit of block index that differs between before and after,
+// unless fn is nil.
+func report(index KeyType, before, after *FiniteBitVector, fn func(KeyType)) {
+	if fn == nil {
+		return
+	}
+	diff := *after
+	for w := range diff {
+		diff[w] ^= before[w]
+	}
+	for i := diff.FindNext(0); i != -1; i = diff.FindNext(i + 1) {
+		fn(index*ElementSize + KeyType(i))
+	}
+}
+
 func (sbv *SparseBitVector) create(index KeyType, prev, next *element) *element {
 	element := &element{index: index, next: next, prev: prev}
 
@@ -25,6 +40,14 @@ func (sbv *SparseBitVector) create(index KeyType, prev, next *element) *element
 		next.prev = element
 	}
 
+	if next == nil {
+		sbv.last = element
+	}
+	if sbv.summary != nil {
+		sbv.summary.add(element)
+	}
+	sbv.size++
+
 	sbv.current = element
 	return element
 }
@@ -33,6 +56,9 @@ func (sbv *SparseBitVector) delete(e *element) {
 	if sbv.start == e {
 		sbv.start = e.next
 	}
+	if sbv.last == e {
+		sbv.last = e.prev
+	}
 	if sbv.current == e {
 		sbv.current = e.prev
 	}
@@ -42,9 +68,41 @@ func (sbv *SparseBitVector) delete(e *element) {
 	if e.next != nil {
 		e.next.prev = e.prev
 	}
+	if sbv.summary != nil {
+		sbv.summary.remove(e)
+	}
+	sbv.size--
+}
+
+// push appends an element after the last one, which must have a lower index.
+func (sbv *SparseBitVector) push(index KeyType, vec *FiniteBitVector) *element {
+	e := sbv.create(index, sbv.last, nil)
+	e.FiniteBitVector = *vec
+	sbv.count += e.Count()
+	sbv.rehash(index, &FiniteBitVector{}, vec)
+	return e
+}
+
+// insert returns the element for index, creating an empty one if necessary.
+func (sbv *SparseBitVector) insert(index KeyType) *element {
+	nearest := sbv.search(index)
+	if nearest == nil {
+		return sbv.create(index, nil, nil)
+	} else if nearest.index < index {
+		return sbv.create(index, nearest, nearest.next)
+	} else if nearest.index > index {
+		return sbv.create(index, nearest.prev, nearest)
+	}
+	return nearest
 }
 
+// search returns the element for index if it exists,
+// otherwise an element adjacent to where it would be inserted.
 func (sbv *SparseBitVector) search(index KeyType) *element {
+	if sbv.spill != nil {
+		sbv.fault(index)
+	}
+
 	if sbv.current == nil {
 		if sbv.start == nil {
 			return nil
@@ -52,21 +110,159 @@ func (sbv *SparseBitVector) search(index KeyType) *element {
 		sbv.current = sbv.start
 	}
 
-	if sbv.current.index > index {
-		for e := sbv.current; e != nil; e = e.prev {
+	if sbv.summary != nil && sbv.current.index != index {
+		if e := sbv.summary.successor(index); e != nil {
 			sbv.current = e
-			if e.index == index {
-				break
-			}
+		} else {
+			sbv.current = sbv.summary.predecessor(index)
+		}
+		return sbv.current
+	}
+
+	if sbv.current.index > index {
+		for sbv.current.prev != nil && sbv.current.index > index {
+			sbv.current = sbv.current.prev
+			sbv.hops++
 		}
 	} else if sbv.current.index < index {
-		for e := sbv.current; e != nil; e = e.next {
-			sbv.current = e
-			if e.index == index {
-				break
-			}
+		for sbv.current.next != nil && sbv.current.index < index {
+			sbv.current = sbv.current.next
+			sbv.hops++
 		}
 	}
 
 	return sbv.current
 }
+
+// seek returns the first element from e onwards with an index of at least index.
+// It counts hops, so it is only used on the receiver of a mutating operation;
+// read-only operands are walked with a cursor.
+func (sbv *SparseBitVector) seek(e *element, index KeyType) *element {
+	if sbv.summary != nil && e != nil && e.next != nil && e.next.index < index {
+		return sbv.summary.successor(index)
+	}
+	for e != nil && e.index < index {
+		e = e.next
+		sbv.hops++
+	}
+	return e
+}
+
+// cursor walks the non-empty blocks of a SparseBitVector in ascending index
+// order, including array and spilled blocks, without modifying it.
+// valid is false once the blocks are exhausted.
+type cursor struct {
+	sbv     *SparseBitVector
+	e       *element
+	arrays  []arrayContainer
+	high    KeyType  // the high of the container holding low
+	low     []uint16 // the remaining offsets of the current container
+	ranges  []spillRange
+	spilled []element
+	current *element        // the current element or spilled block
+	block   FiniteBitVector // the current array block
+	inArray bool
+	index   KeyType
+	valid   bool
+}
+
+// cursor returns a cursor at sbv's first block.
+func (sbv *SparseBitVector) cursor() cursor {
+	c := cursor{sbv: sbv, e: sbv.start, arrays: sbv.arrays}
+	if sbv.spill != nil {
+		c.ranges = sbv.spill.ranges
+	}
+	c.next()
+	return c
+}
+
+// vec returns the bits of the current block.
+func (c *cursor) vec() *FiniteBitVector {
+	if c.inArray {
+		return &c.block
+	}
+	return &c.current.FiniteBitVector
+}
+
+// next advances c to the following block.
+func (c *cursor) next() {
+	for {
+		// refill spilled blocks from the next range
+		if len(c.spilled) == 0 && len(c.ranges) > 0 {
+			c.spilled = c.sbv.readRange(c.ranges[0])
+			c.ranges = c.ranges[1:]
+		}
+
+		// pick the source with the lowest index
+		next := c.e
+		if len(c.spilled) > 0 && (next == nil || c.spilled[0].index < next.index) {
+			next = &c.spilled[0]
+		}
+		if len(c.low) == 0 && len(c.arrays) > 0 {
+			c.high, c.low = c.arrays[0].high, c.arrays[0].low
+			c.arrays = c.arrays[1:]
+		}
+		if len(c.low) > 0 && (next == nil || c.high*spanblocks+KeyType(c.low[0]/ElementSize) < next.index) {
+			c.index, c.block, c.inArray, c.valid = c.high*spanblocks+KeyType(c.low[0]/ElementSize), FiniteBitVector{}, true, true
+			for first := c.low[0] / ElementSize; len(c.low) > 0 && c.low[0]/ElementSize == first; c.low = c.low[1:] {
+				c.block.Set(uint(c.low[0] % ElementSize))
+			}
+			return
+		}
+		if next == nil {
+			c.valid = false
+			return
+		}
+
+		if next == c.e {
+			c.e = c.e.next
+		} else {
+			c.spilled = c.spilled[1:]
+		}
+		if next.Count() != 0 {
+			c.index, c.current, c.inArray, c.valid = next.index, next, false, true
+			return
+		}
+	}
+}
+
+// seek advances c to the first block with an index of at least index.
+func (c *cursor) seek(index KeyType) {
+	if !c.valid || c.index >= index {
+		return
+	}
+	if c.sbv.summary != nil && c.e != nil && len(c.low) == 0 && len(c.arrays) == 0 && len(c.spilled) == 0 && len(c.ranges) == 0 {
+		c.e = c.sbv.summary.successor(index)
+		c.next()
+		return
+	}
+	for c.valid && c.index < index {
+		c.next()
+	}
+}
+
+// blocks calls fn for each non-empty block in ascending index order,
+// including array and spilled blocks, without modifying sbv.
+func (sbv *SparseBitVector) blocks(fn func(index KeyType, vec *FiniteBitVector)) {
+	for c := sbv.cursor(); c.valid; c.next() {
+		fn(c.index, c.vec())
+	}
+}
+
+// blockCount returns the number of non-empty blocks visited by blocks.
+func (sbv *SparseBitVector) blockCount() int {
+	count := 0
+	for e := sbv.start; e != nil; e = e.next {
+		if e.Count() != 0 {
+			count++
+		}
+	}
+	_, blocks, _ := sbv.arrayStats()
+	count += blocks
+	if sbv.spill != nil {
+		for _, r := range sbv.spill.ranges {
+			count += r.blocks
+		}
+	}
+	return count
+}
@@ -0,0 +1,158 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+// AssignTransfer sets sbv to gen ∪ (in − kill), the transfer function
+// of gen/kill dataflow problems, in a single pass.
+// It returns true iff sbv changed.
+func (sbv *SparseBitVector) AssignTransfer(in, gen, kill *SparseBitVector) bool {
+	return sbv.assign([]*SparseBitVector{in, gen, kill}, 2, func(dst *FiniteBitVector, blocks []*FiniteBitVector) {
+		for w := range dst {
+			dst[w] = blocks[1][w] | blocks[0][w]&^blocks[2][w]
+		}
+	})
+}
+
+// SetToUnion sets sbv to the union of a and b.
+// It returns true iff sbv changed.
+func (sbv *SparseBitVector) SetToUnion(a, b *SparseBitVector) bool {
+	return sbv.assign([]*SparseBitVector{a, b}, 2, func(dst *FiniteBitVector, blocks []*FiniteBitVector) {
+		for w := range dst {
+			dst[w] = blocks[0][w] | blocks[1][w]
+		}
+	})
+}
+
+// SetToIntersection sets sbv to the intersection of a and b.
+// It returns true iff sbv changed.
+func (sbv *SparseBitVector) SetToIntersection(a, b *SparseBitVector) bool {
+	return sbv.assign([]*SparseBitVector{a, b}, 1, func(dst *FiniteBitVector, blocks []*FiniteBitVector) {
+		for w := range dst {
+			dst[w] = blocks[0][w] & blocks[1][w]
+		}
+	})
+}
+
+// SetToDifference sets sbv to the intersection of a and the inverse of b.
+// It returns true iff sbv changed.
+func (sbv *SparseBitVector) SetToDifference(a, b *SparseBitVector) bool {
+	return sbv.assign([]*SparseBitVector{a, b}, 1, func(dst *FiniteBitVector, blocks []*FiniteBitVector) {
+		for w := range dst {
+			dst[w] = blocks[0][w] &^ blocks[1][w]
+		}
+	})
+}
+
+// assign overwrites sbv with the blocks op computes from the operands, reusing sbv's elements.
+// Blocks below sbv's threshold are rebuilt in the array.
+// Only indices present in the first drivers operands are computed;
+// the remaining operands are searched for those indices and read as empty where absent.
+func (sbv *SparseBitVector) assign(operands []*SparseBitVector, drivers int, op func(dst *FiniteBitVector, blocks []*FiniteBitVector)) bool {
+	for i, operand := range operands {
+		if operand == sbv {
+			operands[i] = sbv.clone()
+		}
+	}
+	sbv.pageAll()
+	defer sbv.enforce()
+	arrays := sbv.arrays
+	sbv.arrays = nil
+
+	cursors := make([]cursor, len(operands))
+	for i, operand := range operands {
+		cursors[i] = operand.cursor()
+	}
+	blocks := make([]*FiniteBitVector, len(operands))
+	zero := FiniteBitVector{}
+
+	changed := false
+	count := 0
+	hash := uint64(0)
+	reuse := sbv.start
+	for {
+		// the next index comes from the lowest driver
+		var index KeyType
+		found := false
+		for i := range cursors[:drivers] {
+			if c := &cursors[i]; c.valid && (!found || c.index < index) {
+				index = c.index
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+
+		for i := range cursors {
+			c := &cursors[i]
+			if i >= drivers {
+				c.seek(index)
+			}
+			blocks[i] = &zero
+			if c.valid && c.index == index {
+				blocks[i] = c.vec()
+			}
+		}
+
+		block := FiniteBitVector{}
+		op(&block, blocks)
+		for i := range cursors[:drivers] {
+			if c := &cursors[i]; c.valid && c.index == index {
+				c.next()
+			}
+		}
+		if block.Count() == 0 {
+			continue
+		}
+		count += block.Count()
+		hash ^= blockHash(index, &block)
+		if block.Count() < sbv.threshold {
+			sbv.storeArray(index, &block)
+			continue
+		}
+
+		// overwrite the next existing element, or append a new one
+		if reuse == nil {
+			sbv.push(index, &block)
+			changed = true
+			continue
+		}
+		if reuse.index != index {
+			if sbv.summary != nil {
+				sbv.summary.remove(reuse)
+			}
+			reuse.index = index
+			if sbv.summary != nil {
+				sbv.summary.add(reuse)
+			}
+			changed = true
+		}
+		if !reuse.Equals(&block) {
+			reuse.FiniteBitVector = block
+			changed = true
+		}
+		reuse = reuse.next
+	}
+
+	for ; reuse != nil; reuse = reuse.next {
+		sbv.delete(reuse)
+		changed = true
+	}
+	if !equalArrays(arrays, sbv.arrays) {
+		changed = true
+	}
+	sbv.count = count
+	sbv.hash = hash
+	return changed
+}
+
+// clone returns a copy of sbv's contents.
+func (sbv *SparseBitVector) clone() *SparseBitVector {
+	result := New()
+	sbv.blocks(func(index KeyType, vec *FiniteBitVector) {
+		result.push(index, vec)
+	})
+	return result
+}
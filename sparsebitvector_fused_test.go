@@ -0,0 +1,64 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import "testing"
+
+func TestAssignTransfer(t *testing.T) {
+	in := New(0, 1, 2, 1000, 1000000)
+	gen := New(3, 5000)
+	kill := New(1, 1000, 1000000)
+
+	out := New(7, 8, 1<<40)
+	first := out.start
+	if !out.AssignTransfer(in, gen, kill) || out.String() != "[0 2 3 5000]" || out.Count() != 4 {
+		t.Error("incorrect transfer", out)
+	}
+	if out.start != first {
+		t.Error("expected existing elements to be reused")
+	}
+	if out.AssignTransfer(in, gen, kill) {
+		t.Error("unexpected change", out)
+	}
+
+	// the receiver may also be an operand
+	if !in.AssignTransfer(in, gen, kill) || !in.Equals(out) {
+		t.Error("incorrect transfer", in)
+	}
+}
+
+func TestSetToOperations(t *testing.T) {
+	a := New(0, 63, 1000000)
+	b := New(0, 127, 128, 1000000)
+
+	vec := New()
+	if !vec.SetToUnion(a, b) || vec.String() != "[0 63 127 128 1000000]" || vec.Count() != 5 {
+		t.Error("incorrect union", vec)
+	}
+	if vec.SetToUnion(b, a) {
+		t.Error("unexpected change", vec)
+	}
+	if !vec.SetToIntersection(a, b) || vec.String() != "[0 1000000]" || vec.Count() != 2 {
+		t.Error("incorrect intersection", vec)
+	}
+	if !vec.SetToDifference(a, b) || vec.String() != "[63]" || vec.Count() != 1 {
+		t.Error("incorrect difference", vec)
+	}
+	if !vec.SetToDifference(b, b) || vec.Count() != 0 || vec.start != nil {
+		t.Error("incorrect difference", vec)
+	}
+
+	vec = NewAdaptive(2, 5, 1<<50)
+	vec.EnableSummary()
+	if !vec.SetToUnion(vec, b) || vec.String() != "[0 5 127 128 1000000 1125899906842624]" {
+		t.Error("incorrect union", vec)
+	}
+	if !vec.SetToIntersection(a, vec) || vec.String() != "[0 1000000]" {
+		t.Error("incorrect intersection", vec)
+	}
+	if !vec.Test(1000000) || vec.Test(1<<50) {
+		t.Error("incorrect summary", vec)
+	}
+}
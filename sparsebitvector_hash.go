@@ -0,0 +1,85 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+// mix is the 64 bit finalizer from MurmurHash3, which scrambles h into a well distributed hash.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// blockHash returns the hash of a non-empty block.
+func blockHash(index KeyType, vec *FiniteBitVector) uint64 {
+	h := mix(uint64(index) ^ 0x9e3779b97f4a7c15)
+	for _, word := range vec {
+		h = mix(h ^ uint64(word))
+	}
+	return h
+}
+
+// rehash updates sbv's hash for block index changing from before to after.
+func (sbv *SparseBitVector) rehash(index KeyType, before, after *FiniteBitVector) {
+	if before.Count() != 0 {
+		sbv.hash ^= blockHash(index, before)
+	}
+	if after.Count() != 0 {
+		sbv.hash ^= blockHash(index, after)
+	}
+}
+
+// Hash returns a hash of sbv's true bits which is stable across processes
+// and independent of how sbv was built or accessed.
+// It is the XOR of a hash of each block, and is maintained as sbv is modified.
+func (sbv *SparseBitVector) Hash() uint64 {
+	return sbv.hash
+}
+
+// HashWithSeed returns a hash of sbv's true bits which is stable across
+// processes and independent of how sbv was built or accessed.
+func (sbv *SparseBitVector) HashWithSeed(seed uint64) uint64 {
+	h := mix(seed ^ 0x9e3779b97f4a7c15)
+	sbv.blocks(func(index KeyType, vec *FiniteBitVector) {
+		h = mix(h ^ uint64(index))
+		for _, word := range vec {
+			h = mix(h ^ uint64(word))
+		}
+	})
+	return mix(h ^ uint64(sbv.count))
+}
+
+// Interner maps SparseBitVectors with equal contents to a single canonical instance.
+type Interner struct {
+	buckets map[uint64][]*SparseBitVector
+	size    int
+}
+
+// NewInterner creates an empty Interner.
+func NewInterner() *Interner {
+	return &Interner{buckets: make(map[uint64][]*SparseBitVector)}
+}
+
+// Intern returns the canonical vector equal to sbv, adding a copy of sbv if there is none.
+// Canonical vectors are shared and must not be modified.
+func (in *Interner) Intern(sbv *SparseBitVector) *SparseBitVector {
+	h := sbv.Hash()
+	for _, canonical := range in.buckets[h] {
+		if canonical.Equals(sbv) {
+			return canonical
+		}
+	}
+	canonical := sbv.clone()
+	in.buckets[h] = append(in.buckets[h], canonical)
+	in.size++
+	return canonical
+}
+
+// Len returns the number of canonical vectors.
+func (in *Interner) Len() int {
+	return in.size
+}
@@ -0,0 +1,139 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// fullHash recomputes the hash maintained by sbv.
+func fullHash(sbv *SparseBitVector) uint64 {
+	h := uint64(0)
+	sbv.blocks(func(index KeyType, vec *FiniteBitVector) {
+		h ^= blockHash(index, vec)
+	})
+	return h
+}
+
+func TestHash(t *testing.T) {
+	a := New(1, 2, 1000, 1<<40)
+	b := NewAdaptive(4)
+	for _, key := range []KeyType{1 << 40, 7, 1000, 2, 1} {
+		b.Set(key)
+	}
+	b.Unset(7)
+	b.Test(1)
+
+	if a.Hash() != b.Hash() || a.HashWithSeed(42) != b.HashWithSeed(42) {
+		t.Error("unequal hashes", a, b)
+	}
+	if a.HashWithSeed(42) == a.HashWithSeed(43) {
+		t.Error("seed ignored", a)
+	}
+	if New().Hash() != 0 || New().Hash() == New(0).Hash() || New(0).Hash() == New(1).Hash() || New(0).Hash() == New(ElementSize).Hash() {
+		t.Error("colliding hashes")
+	}
+	if New(1, 2, 1000, 1<<40).Hash() != 1934446988173458628 {
+		t.Error("unstable hash", New(1, 2, 1000, 1<<40).Hash())
+	}
+
+	// the hash follows modifications
+	h := a.Hash()
+	a.Set(5)
+	if a.Hash() == h || a.Equals(b) {
+		t.Error("stale hash", a)
+	}
+	a.Unset(5)
+	if a.Hash() != h || !a.Equals(b) {
+		t.Error("incorrect hash", a)
+	}
+	a.UnionWith(New(3))
+	if a.Hash() != fullHash(a) || a.Hash() != New(1, 2, 3, 1000, 1<<40).Hash() {
+		t.Error("stale hash", a)
+	}
+	a.IntersectWithComplement(New(3))
+	if a.Hash() != h {
+		t.Error("incorrect hash", a)
+	}
+
+	spilled := New()
+	for key := KeyType(0); key < 100*ElementSize; key += ElementSize {
+		spilled.Set(key)
+	}
+	h = spilled.Hash()
+	spilled.SetMemoryBudget(1)
+	if spilled.Hash() != h || fullHash(spilled) != h {
+		t.Error("incorrect spilled hash", spilled)
+	}
+}
+
+func TestHashRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() *SparseBitVector {
+		vec := New()
+		switch r.Intn(4) {
+		case 1:
+			vec = NewAdaptive(3)
+		case 2:
+			vec.EnableSummary()
+		case 3:
+			vec.SetMemoryBudget(500)
+		}
+		for i := r.Intn(50); i > 0; i-- {
+			vec.Set(KeyType(r.Intn(3000)))
+		}
+		return vec
+	}
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		switch r.Intn(9) {
+		case 0:
+			a.Unset(KeyType(r.Intn(3000)))
+		case 1:
+			a.UnionWith(b)
+		case 2:
+			a.IntersectWith(b)
+		case 3:
+			a.IntersectWithComplement(b)
+		case 4:
+			a.SetToDifference(a, b)
+		case 5:
+			a.UnionWithDense(NewBitVectorOf(b))
+		case 6:
+			a.IntersectWithDense(NewBitVectorOf(b))
+		case 7:
+			data, _ := b.MarshalBinary()
+			a.UnmarshalBinary(data)
+		case 8:
+			a = UnionAll(a, b)
+		}
+		if a.Hash() != fullHash(a) {
+			t.Error("incorrect hash", a)
+		}
+		if a.Equals(b) != (a.String() == b.String()) {
+			t.Error("incorrect equality", a, b)
+		}
+	}
+}
+
+func TestInterner(t *testing.T) {
+	in := NewInterner()
+	a := New(1, 2, 3)
+	canonical := in.Intern(a)
+	if canonical == a || !canonical.Equals(a) {
+		t.Error("incorrect canonical vector", canonical)
+	}
+	if in.Intern(New(3, 2, 1)) != canonical || in.Intern(canonical) != canonical {
+		t.Error("duplicate canonical vector")
+	}
+	a.Set(4)
+	if in.Intern(a) == canonical || canonical.Count() != 3 {
+		t.Error("canonical vector modified", canonical)
+	}
+	if in.Intern(New()) == in.Intern(New(0)) || in.Len() != 4 {
+		t.Error("incorrect interner size", in.Len())
+	}
+}
@@ -0,0 +1,173 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"container/heap"
+	"math/bits"
+	"sort"
+)
+
+// cursorHeap orders block cursors by index for a k-way merge.
+type cursorHeap []*cursor
+
+func (h cursorHeap) Len() int            { return len(h) }
+func (h cursorHeap) Less(i, j int) bool  { return h[i].index < h[j].index }
+func (h cursorHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *cursorHeap) Push(x interface{}) { *h = append(*h, x.(*cursor)) }
+func (h *cursorHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// merge calls fn with each block index present in any of vs, in ascending
+// order, along with the blocks of every vector containing that index.
+func merge(vs []*SparseBitVector, fn func(index KeyType, vecs []*FiniteBitVector)) {
+	cursors := make([]cursor, len(vs))
+	h := cursorHeap{}
+	for i, sbv := range vs {
+		if cursors[i] = sbv.cursor(); cursors[i].valid {
+			h = append(h, &cursors[i])
+		}
+	}
+	heap.Init(&h)
+
+	vecs := make([]*FiniteBitVector, 0, len(vs))
+	matched := make([]*cursor, 0, len(vs))
+	for len(h) > 0 {
+		index := h[0].index
+		vecs, matched = vecs[:0], matched[:0]
+		for len(h) > 0 && h[0].index == index {
+			c := heap.Pop(&h).(*cursor)
+			vecs = append(vecs, c.vec())
+			matched = append(matched, c)
+		}
+		fn(index, vecs)
+
+		// advance only after fn, since an array block is overwritten by next
+		for _, c := range matched {
+			if c.next(); c.valid {
+				heap.Push(&h, c)
+			}
+		}
+	}
+}
+
+// UnionAll returns a new SparseBitVector containing the union of vs.
+func UnionAll(vs ...*SparseBitVector) *SparseBitVector {
+	result := New()
+	merge(vs, func(index KeyType, vecs []*FiniteBitVector) {
+		block := *vecs[0]
+		for _, vec := range vecs[1:] {
+			block.UnionWith(vec)
+		}
+		if block.Count() != 0 {
+			result.push(index, &block)
+		}
+	})
+	return result
+}
+
+// IntersectAll returns a new SparseBitVector containing the intersection of vs.
+// The smallest vector drives the intersection, and the others seek
+// directly to its element indices.
+func IntersectAll(vs ...*SparseBitVector) *SparseBitVector {
+	result := New()
+	if len(vs) == 0 {
+		return result
+	}
+
+	sorted := append([]*SparseBitVector{}, vs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].count < sorted[j].count })
+
+	cursors := make([]cursor, len(sorted))
+	for i, sbv := range sorted {
+		if cursors[i] = sbv.cursor(); !cursors[i].valid {
+			return result
+		}
+	}
+
+	for index := cursors[0].index; ; {
+		// leapfrog until every cursor reaches the same index
+		matched := true
+		for i := range cursors {
+			if cursors[i].seek(index); !cursors[i].valid {
+				return result
+			}
+			if cursors[i].index > index {
+				index = cursors[i].index
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		block := *cursors[0].vec()
+		for i := range cursors[1:] {
+			block.IntersectWith(cursors[i+1].vec())
+		}
+		if block.Count() != 0 {
+			result.push(index, &block)
+		}
+		if cursors[0].next(); !cursors[0].valid {
+			return result
+		}
+		index = cursors[0].index
+	}
+}
+
+// AtLeast returns a new SparseBitVector containing the bits which are true in at least k of vs.
+// The bits are counted a word at a time using bit-sliced counters.
+func AtLeast(k int, vs ...*SparseBitVector) *SparseBitVector {
+	if k < 1 {
+		k = 1
+	}
+	result := New()
+	if k > len(vs) {
+		return result
+	}
+
+	// counters[j] holds bit j of every bit's count
+	counters := make([]FiniteBitVector, bits.Len(uint(len(vs))))
+	merge(vs, func(index KeyType, vecs []*FiniteBitVector) {
+		if len(vecs) < k {
+			return
+		}
+		for j := range counters {
+			counters[j].Clear()
+		}
+		for _, vec := range vecs {
+			for w, carry := range vec {
+				for j := 0; carry != 0; j++ {
+					counters[j][w], carry = counters[j][w]^carry, counters[j][w]&carry
+				}
+			}
+		}
+
+		// compare each count with k from the most significant bit
+		block := FiniteBitVector{}
+		for w := range block {
+			var greater elementwordtype
+			equal := ^elementwordtype(0)
+			for j := len(counters) - 1; j >= 0; j-- {
+				if k&(1<<uint(j)) != 0 {
+					equal &= counters[j][w]
+				} else {
+					greater |= equal & counters[j][w]
+					equal &^= counters[j][w]
+				}
+			}
+			block[w] = greater | equal
+		}
+		if block.Count() != 0 {
+			result.push(index, &block)
+		}
+	})
+	return result
+}
@@ -0,0 +1,86 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import "testing"
+
+func TestUnionAll(t *testing.T) {
+	if vec := UnionAll(); vec.Count() != 0 {
+		t.Error("incorrect union", vec)
+	}
+
+	vec1 := New(0, 63, 1000000)
+	vec2 := New(0, 127, 128, 1000000)
+	vec3 := NewAdaptive(2, 5, 1<<40)
+	if vec := UnionAll(vec1, vec2, vec3, New()); vec.String() != "[0 5 63 127 128 1000000 1099511627776]" || vec.Count() != 7 {
+		t.Error("incorrect union", vec)
+	}
+	if vec := UnionAll(vec1, vec1); !vec.Equals(vec1) {
+		t.Error("incorrect union", vec)
+	}
+}
+
+func TestIntersectAll(t *testing.T) {
+	if vec := IntersectAll(); vec.Count() != 0 {
+		t.Error("incorrect intersection", vec)
+	}
+
+	vec1 := New(0, 63, 127, 1000, 1000000)
+	vec2 := New(0, 127, 128, 1000, 1000000)
+	vec3 := NewAdaptive(2, 0, 127, 1000000, 1<<40)
+	if vec := IntersectAll(vec1, vec2, vec3); vec.String() != "[0 127 1000000]" || vec.Count() != 3 {
+		t.Error("incorrect intersection", vec)
+	}
+	if vec := IntersectAll(vec1, vec2); vec.String() != "[0 127 1000 1000000]" {
+		t.Error("incorrect intersection", vec)
+	}
+	if vec := IntersectAll(vec1, New(1, 64)); vec.Count() != 0 || vec.start != nil {
+		t.Error("incorrect intersection", vec)
+	}
+	if vec := IntersectAll(vec1, New()); vec.Count() != 0 {
+		t.Error("incorrect intersection", vec)
+	}
+
+	vec2.EnableSummary()
+	if vec := IntersectAll(vec2, New(1000000)); vec.String() != "[1000000]" {
+		t.Error("incorrect intersection", vec)
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	vec1 := New(0, 1, 2, 1000)
+	vec2 := New(1, 2, 3, 1000)
+	vec3 := New(2, 3, 4, 1000000)
+	vec4 := New(2, 1000)
+
+	if vec := AtLeast(1, vec1, vec2, vec3, vec4); !vec.Equals(UnionAll(vec1, vec2, vec3, vec4)) {
+		t.Error("incorrect union", vec)
+	}
+	if vec := AtLeast(2, vec1, vec2, vec3, vec4); vec.String() != "[1 2 3 1000]" {
+		t.Error("incorrect result", vec)
+	}
+	if vec := AtLeast(3, vec1, vec2, vec3, vec4); vec.String() != "[2 1000]" {
+		t.Error("incorrect result", vec)
+	}
+	if vec := AtLeast(4, vec1, vec2, vec3, vec4); !vec.Equals(IntersectAll(vec1, vec2, vec3, vec4)) {
+		t.Error("incorrect intersection", vec)
+	}
+	if vec := AtLeast(5, vec1, vec2, vec3, vec4); vec.Count() != 0 {
+		t.Error("incorrect result", vec)
+	}
+
+	// more inputs than fit in a single counter bit
+	vs := []*SparseBitVector{}
+	for i := KeyType(0); i < 9; i++ {
+		vs = append(vs, New(100, 200+i))
+	}
+	vs = append(vs, New(200))
+	if vec := AtLeast(9, vs...); vec.String() != "[100]" {
+		t.Error("incorrect result", vec)
+	}
+	if vec := AtLeast(2, vs...); vec.String() != "[100 200]" {
+		t.Error("incorrect result", vec)
+	}
+}
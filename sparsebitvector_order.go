@@ -0,0 +1,151 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// Cmp compares the true bits of a and b, in ascending order, lexicographically.
+// It returns -1 if a precedes b, 0 if they are equal and 1 if a follows b.
+// A vector precedes the vectors it is a proper prefix of, so the empty vector precedes all others.
+func Cmp(a, b *SparseBitVector) int {
+	if a == b {
+		return 0
+	}
+	c1, c2 := a.cursor(), b.cursor()
+	for c1.valid && c2.valid && c1.index == c2.index && c1.vec().Equals(c2.vec()) {
+		c1.next()
+		c2.next()
+	}
+	switch {
+	case !c1.valid && !c2.valid:
+		return 0
+	case !c1.valid:
+		return -1
+	case !c2.valid:
+		return 1
+	case c1.index < c2.index:
+		return -1
+	case c1.index > c2.index:
+		return 1
+	}
+
+	// the lowest differing bit is in one vector; that vector precedes
+	// unless the other has no later bit and is therefore its prefix
+	v1, v2 := c1.vec(), c2.vec()
+	for w := range v1 {
+		diff := v1[w] ^ v2[w]
+		if diff == 0 {
+			continue
+		}
+		bit := uint(bits.TrailingZeros64(uint64(diff)))
+		if v1[w]>>bit&1 == 1 {
+			if c2.hasAfter(w, bit) {
+				return -1
+			}
+			return 1
+		}
+		if c1.hasAfter(w, bit) {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+// hasAfter returns true iff a true bit follows bit in word w of the current block or in a later block.
+func (c *cursor) hasAfter(w int, bit uint) bool {
+	vec := c.vec()
+	if vec[w]>>bit>>1 != 0 {
+		return true
+	}
+	for _, word := range vec[w+1:] {
+		if word != 0 {
+			return true
+		}
+	}
+	c.next()
+	return c.valid
+}
+
+// Sort sorts vs in ascending order of Cmp.
+func Sort(vs []*SparseBitVector) {
+	sort.Slice(vs, func(i, j int) bool { return Cmp(vs[i], vs[j]) < 0 })
+}
+
+// Dedup sorts vs and returns its prefix holding the first of each group of equal vectors.
+func Dedup(vs []*SparseBitVector) []*SparseBitVector {
+	Sort(vs)
+	result := vs[:0]
+	for i, v := range vs {
+		if i == 0 || !v.Equals(result[len(result)-1]) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// VectorSet is a set of distinct SparseBitVectors kept in ascending order of Cmp.
+type VectorSet struct {
+	vs []*SparseBitVector
+}
+
+// NewVectorSet creates a VectorSet, optionally initialized with copies of vs.
+func NewVectorSet(vs ...*SparseBitVector) *VectorSet {
+	result := &VectorSet{}
+	for _, v := range vs {
+		result.Insert(v)
+	}
+	return result
+}
+
+// Search returns the position of the first vector in s which does not precede v,
+// and true iff that vector is equal to v.
+func (s *VectorSet) Search(v *SparseBitVector) (int, bool) {
+	i := sort.Search(len(s.vs), func(i int) bool { return Cmp(s.vs[i], v) >= 0 })
+	return i, i < len(s.vs) && s.vs[i].Equals(v)
+}
+
+// Insert adds a copy of v to s.
+// It returns true iff s changed.
+func (s *VectorSet) Insert(v *SparseBitVector) bool {
+	i, found := s.Search(v)
+	if found {
+		return false
+	}
+	s.vs = append(s.vs, nil)
+	copy(s.vs[i+1:], s.vs[i:])
+	s.vs[i] = v.clone()
+	return true
+}
+
+// Remove removes the vector equal to v from s.
+// It returns true iff s changed.
+func (s *VectorSet) Remove(v *SparseBitVector) bool {
+	i, found := s.Search(v)
+	if found {
+		s.vs = append(s.vs[:i], s.vs[i+1:]...)
+	}
+	return found
+}
+
+// Contains returns true iff s contains a vector equal to v.
+func (s *VectorSet) Contains(v *SparseBitVector) bool {
+	_, found := s.Search(v)
+	return found
+}
+
+// Len returns the number of vectors in s.
+func (s *VectorSet) Len() int {
+	return len(s.vs)
+}
+
+// At returns the i'th vector in ascending order.
+// The result is shared with s and must not be modified.
+func (s *VectorSet) At(i int) *SparseBitVector {
+	return s.vs[i]
+}
@@ -0,0 +1,80 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import "testing"
+
+func TestCmp(t *testing.T) {
+	// in ascending order
+	ordered := []*SparseBitVector{
+		New(),
+		New(0),
+		New(0, 1),
+		New(0, 1, 2),
+		New(0, 1, 1000),
+		New(0, 1, 1<<40),
+		New(0, 2),
+		New(0, 63, 64),
+		New(0, 64),
+		New(0, ElementSize),
+		New(5),
+		New(ElementSize),
+		New(ElementSize, 1<<62),
+		New(1 << 62),
+	}
+	for i, a := range ordered {
+		for j, b := range ordered {
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			if c := Cmp(a, b); c != expected {
+				t.Error("incorrect comparison", a, b, c)
+			}
+		}
+	}
+
+	adaptive := NewAdaptive(4, 0, 1, 1000)
+	if Cmp(adaptive, ordered[4]) != 0 || Cmp(adaptive, ordered[5]) != -1 {
+		t.Error("incorrect adaptive comparison", adaptive)
+	}
+}
+
+func TestSortAndDedup(t *testing.T) {
+	vs := []*SparseBitVector{New(3), New(1, 2), New(), New(3), New(1), New(1, 2)}
+	vs = Dedup(vs)
+	expected := []string{"[]", "[1]", "[1 2]", "[3]"}
+	if len(vs) != len(expected) {
+		t.Error("incorrect dedup", vs)
+	}
+	for i := range expected {
+		if i < len(vs) && vs[i].String() != expected[i] {
+			t.Error("incorrect order", vs)
+		}
+	}
+}
+
+func TestVectorSet(t *testing.T) {
+	s := NewVectorSet(New(3), New(1, 2), New(3))
+	if s.Len() != 2 || s.At(0).String() != "[1 2]" || s.At(1).String() != "[3]" {
+		t.Error("incorrect set", s.vs)
+	}
+	v := New(1)
+	if !s.Insert(v) || s.Insert(New(1)) || s.At(0).String() != "[1]" {
+		t.Error("incorrect insert", s.vs)
+	}
+	v.Set(5)
+	if !s.Contains(New(1)) || s.Contains(v) {
+		t.Error("inserted vector shared", s.vs)
+	}
+	if i, found := s.Search(New(2)); found || i != 2 {
+		t.Error("incorrect search", i, found)
+	}
+	if !s.Remove(New(1, 2)) || s.Remove(New(1, 2)) || s.Len() != 2 {
+		t.Error("incorrect remove", s.vs)
+	}
+}
@@ -0,0 +1,63 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import "math"
+
+// The similarity measures are computed from a single intersection pass
+// over word popcounts, and do not allocate unless spilled blocks must be
+// read back from the spill file.
+// Where a measure's denominator is zero, it is 1 if both vectors are empty and 0 otherwise.
+
+// DiffSizes returns the number of true bits only in a, only in b, and in both.
+func DiffSizes(a, b *SparseBitVector) (int, int, int) {
+	intersection := a.IntersectionSize(b)
+	return a.count - intersection, b.count - intersection, intersection
+}
+
+// ratio returns n/d, or the value for a zero denominator.
+func (sbv *SparseBitVector) ratio(sbv2 *SparseBitVector, n, d float64) float64 {
+	if d == 0 {
+		if sbv.count == 0 && sbv2.count == 0 {
+			return 1
+		}
+		return 0
+	}
+	return n / d
+}
+
+// Jaccard returns |sbv ∩ sbv2| / |sbv ∪ sbv2|.
+func (sbv *SparseBitVector) Jaccard(sbv2 *SparseBitVector) float64 {
+	union, intersection := sbv.UnionAndIntersectionSize(sbv2)
+	return sbv.ratio(sbv2, float64(intersection), float64(union))
+}
+
+// Dice returns 2|sbv ∩ sbv2| / (|sbv| + |sbv2|).
+func (sbv *SparseBitVector) Dice(sbv2 *SparseBitVector) float64 {
+	intersection := sbv.IntersectionSize(sbv2)
+	return sbv.ratio(sbv2, float64(2*intersection), float64(sbv.count+sbv2.count))
+}
+
+// Overlap returns |sbv ∩ sbv2| / min(|sbv|, |sbv2|).
+func (sbv *SparseBitVector) Overlap(sbv2 *SparseBitVector) float64 {
+	intersection := sbv.IntersectionSize(sbv2)
+	smaller := sbv.count
+	if sbv2.count < smaller {
+		smaller = sbv2.count
+	}
+	return sbv.ratio(sbv2, float64(intersection), float64(smaller))
+}
+
+// Cosine returns |sbv ∩ sbv2| / sqrt(|sbv| |sbv2|).
+func (sbv *SparseBitVector) Cosine(sbv2 *SparseBitVector) float64 {
+	intersection := sbv.IntersectionSize(sbv2)
+	return sbv.ratio(sbv2, float64(intersection), math.Sqrt(float64(sbv.count)*float64(sbv2.count)))
+}
+
+// HammingDistance returns the number of bits which differ between sbv and sbv2.
+func (sbv *SparseBitVector) HammingDistance(sbv2 *SparseBitVector) int {
+	onlyA, onlyB, _ := DiffSizes(sbv, sbv2)
+	return onlyA + onlyB
+}
@@ -0,0 +1,58 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	a := New(1, 2, 3, 4, 1000)
+	b := NewAdaptive(4, 3, 4, 1000, 1<<40)
+
+	if onlyA, onlyB, both := DiffSizes(a, b); onlyA != 2 || onlyB != 1 || both != 3 {
+		t.Error("incorrect diff sizes", onlyA, onlyB, both)
+	}
+	if j := a.Jaccard(b); j != 0.5 {
+		t.Error("incorrect jaccard", j)
+	}
+	if d := a.Dice(b); math.Abs(d-6.0/9) > 1e-12 {
+		t.Error("incorrect dice", d)
+	}
+	if o := a.Overlap(b); o != 0.75 {
+		t.Error("incorrect overlap", o)
+	}
+	if c := a.Cosine(b); math.Abs(c-3/math.Sqrt(20)) > 1e-12 {
+		t.Error("incorrect cosine", c)
+	}
+	if h := a.HammingDistance(b); h != 3 || b.HammingDistance(a) != 3 {
+		t.Error("incorrect hamming distance", h)
+	}
+
+	empty := New()
+	if empty.Jaccard(New()) != 1 || empty.Dice(New()) != 1 || empty.Overlap(New()) != 1 || empty.Cosine(New()) != 1 {
+		t.Error("incorrect empty similarity")
+	}
+	if empty.Jaccard(a) != 0 || a.Overlap(empty) != 0 || a.Cosine(empty) != 0 || a.HammingDistance(empty) != 5 {
+		t.Error("incorrect similarity with empty")
+	}
+}
+
+func TestSimilarityAllocations(t *testing.T) {
+	// array blocks are read in place
+	a, b := NewAdaptive(8, 1, 2, 3, 1000, 1<<40), New(2, 3, 1<<40, 1<<50)
+	allocs := testing.AllocsPerRun(100, func() {
+		DiffSizes(a, b)
+		a.Jaccard(b)
+		a.Dice(b)
+		a.Overlap(b)
+		a.Cosine(b)
+		a.HammingDistance(b)
+	})
+	if allocs != 0 {
+		t.Error("unexpected allocations", allocs)
+	}
+}
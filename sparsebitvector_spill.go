@@ -0,0 +1,291 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"os"
+	"sort"
+	"unsafe"
+)
+
+// spillchunk is the maximum number of elements written to a single spilled range.
+const spillchunk = 64
+
+// spillRange describes consecutive elements which were moved to the spill file.
+// No element between lo and hi is held in memory while the range is spilled.
+type spillRange struct {
+	lo, hi KeyType // first and last element index
+	offset int64   // position of the encoded blocks in the file
+	blocks int
+}
+
+// extent is a free region of the spill file.
+type extent struct {
+	offset, size int64
+}
+
+// spill holds the state of a SparseBitVector with a memory budget.
+type spill struct {
+	budget  int
+	file    *os.File
+	end     int64
+	free    []extent // free regions before end, in ascending order of offset
+	ranges  []spillRange
+	spills  int
+	pageIns int
+}
+
+// SetMemoryBudget limits the memory used by sbv's elements to about budget bytes.
+// When the budget is exceeded, ranges of elements far from the most recent
+// access are moved to a temporary file and paged back in when accessed.
+// Mutating set operations page in the whole receiver for their duration,
+// while read-only operations and operands read spilled ranges in place.
+// The space of paged in ranges is reused by later spills.
+// A budget of 0 removes the limit, like Close.
+// I/O errors while paging cause a panic.
+func (sbv *SparseBitVector) SetMemoryBudget(budget int) {
+	if budget <= 0 {
+		sbv.Close()
+		return
+	}
+	if sbv.spill == nil {
+		sbv.spill = &spill{}
+	}
+	sbv.spill.budget = budget
+	sbv.enforce()
+}
+
+// Close removes sbv's memory budget, pages everything back in and closes the spill file.
+// A SparseBitVector with a memory budget holds an open temporary file,
+// which is otherwise only closed once sbv is garbage collected.
+// sbv remains usable after Close.
+func (sbv *SparseBitVector) Close() error {
+	if sbv.spill == nil {
+		return nil
+	}
+	sbv.pageAll()
+	var err error
+	if sbv.spill.file != nil {
+		err = sbv.spill.file.Close()
+	}
+	sbv.spill = nil
+	return err
+}
+
+// memory returns the number of bytes held by elements, the array and the summary.
+func (sbv *SparseBitVector) memory() int {
+	_, _, bytes := sbv.arrayStats()
+	if sbv.summary != nil {
+		bytes += sbv.summary.bytes()
+	}
+	return sbv.size*int(unsafe.Sizeof(element{})) + bytes
+}
+
+// enforce spills elements until sbv fits its memory budget.
+func (sbv *SparseBitVector) enforce() {
+	if sbv.spill == nil {
+		return
+	}
+	for sbv.memory() > sbv.spill.budget && sbv.spillChunk() {
+	}
+}
+
+// spilledBetween returns true iff a spilled range lies between element indices lo and hi.
+func (s *spill) spilledBetween(lo, hi KeyType) bool {
+	i := sort.Search(len(s.ranges), func(i int) bool { return s.ranges[i].lo > lo })
+	return i < len(s.ranges) && s.ranges[i].lo < hi
+}
+
+// coldRun returns up to spillchunk consecutive elements from one end of the list,
+// stopping at the current element or a spilled range.
+func (sbv *SparseBitVector) coldRun(fromStart bool) (first, last *element, n int) {
+	if fromStart {
+		first, last = sbv.start, sbv.start
+		if first == nil || first == sbv.current {
+			return nil, nil, 0
+		}
+		for n = 1; n < spillchunk && last.next != nil && last.next != sbv.current; n++ {
+			if sbv.spill.spilledBetween(last.index, last.next.index) {
+				break
+			}
+			last = last.next
+		}
+	} else {
+		first, last = sbv.last, sbv.last
+		if last == nil || last == sbv.current {
+			return nil, nil, 0
+		}
+		for n = 1; n < spillchunk && first.prev != nil && first.prev != sbv.current; n++ {
+			if sbv.spill.spilledBetween(first.prev.index, first.index) {
+				break
+			}
+			first = first.prev
+		}
+	}
+	return first, last, n
+}
+
+// spillChunk spills a run of elements from whichever end of the list is
+// furthest from the current element, and returns false if nothing could be spilled.
+func (sbv *SparseBitVector) spillChunk() bool {
+	if sbv.start == nil {
+		return false
+	}
+
+	// prefer the end furthest from the most recent access
+	fromStart := sbv.current == nil || sbv.current.index-sbv.start.index >= sbv.last.index-sbv.current.index
+	first, last, n := sbv.coldRun(fromStart)
+	if n == 0 {
+		first, last, n = sbv.coldRun(!fromStart)
+	}
+	if n == 0 {
+		return false
+	}
+
+	// write the blocks to a free extent or the end of the file
+	if sbv.spill.file == nil {
+		file, err := os.CreateTemp("", "sparsebitvector-")
+		if err != nil {
+			panic(err)
+		}
+		// the file remains usable until it is closed
+		os.Remove(file.Name())
+		sbv.spill.file = file
+	}
+	buf := make([]byte, n*blockbytes)
+	r := spillRange{lo: first.index, hi: last.index, offset: sbv.spill.allocate(int64(len(buf))), blocks: n}
+	for i, e := 0, first; i < n; i, e = i+1, e.next {
+		encodeBlock(buf[i*blockbytes:], e.index, &e.FiniteBitVector)
+	}
+	if _, err := sbv.spill.file.WriteAt(buf, r.offset); err != nil {
+		panic(err)
+	}
+
+	for i, e := 0, first; i < n; i, e = i+1, e.next {
+		sbv.delete(e)
+	}
+	i := sort.Search(len(sbv.spill.ranges), func(i int) bool { return sbv.spill.ranges[i].lo > r.lo })
+	sbv.spill.ranges = append(sbv.spill.ranges, spillRange{})
+	copy(sbv.spill.ranges[i+1:], sbv.spill.ranges[i:])
+	sbv.spill.ranges[i] = r
+	sbv.spill.spills++
+	return true
+}
+
+// readRange returns the elements stored in a spilled range.
+func (sbv *SparseBitVector) readRange(r spillRange) []element {
+	buf := make([]byte, r.blocks*blockbytes)
+	if _, err := sbv.spill.file.ReadAt(buf, r.offset); err != nil {
+		panic(err)
+	}
+	result := make([]element, r.blocks)
+	for i := range result {
+		result[i].index, result[i].FiniteBitVector = decodeBlock(buf[i*blockbytes:])
+	}
+	return result
+}
+
+// pageIn moves the i'th spilled range back into the element list.
+func (sbv *SparseBitVector) pageIn(i int) {
+	r := sbv.spill.ranges[i]
+	sbv.spill.ranges = append(sbv.spill.ranges[:i], sbv.spill.ranges[i+1:]...)
+	for _, block := range sbv.readRange(r) {
+		sbv.insert(block.index).FiniteBitVector = block.FiniteBitVector
+	}
+	sbv.spill.release(r.offset, int64(r.blocks*blockbytes))
+	sbv.spill.pageIns++
+}
+
+// fault pages in the spilled range containing the element index, if any.
+func (sbv *SparseBitVector) fault(index KeyType) {
+	ranges := sbv.spill.ranges
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].hi >= index })
+	if i < len(ranges) && ranges[i].lo <= index {
+		sbv.pageIn(i)
+	}
+}
+
+// pageAll pages in every spilled range.
+func (sbv *SparseBitVector) pageAll() {
+	for sbv.spill != nil && len(sbv.spill.ranges) > 0 {
+		sbv.pageIn(len(sbv.spill.ranges) - 1)
+	}
+}
+
+// nextRange returns the position of the first spilled range ending at or after index, or -1.
+func (sbv *SparseBitVector) nextRange(index KeyType) int {
+	if sbv.spill == nil {
+		return -1
+	}
+	ranges := sbv.spill.ranges
+	if i := sort.Search(len(ranges), func(i int) bool { return ranges[i].hi >= index }); i < len(ranges) {
+		return i
+	}
+	return -1
+}
+
+// prevRange returns the position of the last spilled range starting at or before index, or -1.
+func (sbv *SparseBitVector) prevRange(index KeyType) int {
+	if sbv.spill == nil {
+		return -1
+	}
+	ranges := sbv.spill.ranges
+	return sort.Search(len(ranges), func(i int) bool { return ranges[i].lo > index }) - 1
+}
+
+// allocate returns the offset of size bytes in the spill file,
+// preferring the first free extent which is large enough.
+func (s *spill) allocate(size int64) int64 {
+	for i, x := range s.free {
+		if x.size < size {
+			continue
+		}
+		if x.size == size {
+			s.free = append(s.free[:i], s.free[i+1:]...)
+		} else {
+			s.free[i] = extent{x.offset + size, x.size - size}
+		}
+		return x.offset
+	}
+	offset := s.end
+	s.end += size
+	return offset
+}
+
+// release adds size bytes at offset to the free extents, merging neighbouring
+// extents and truncating the file when the freed space reaches its end.
+func (s *spill) release(offset, size int64) {
+	i := sort.Search(len(s.free), func(i int) bool { return s.free[i].offset > offset })
+	s.free = append(s.free, extent{})
+	copy(s.free[i+1:], s.free[i:])
+	s.free[i] = extent{offset, size}
+	if i+1 < len(s.free) && s.free[i].offset+s.free[i].size == s.free[i+1].offset {
+		s.free[i].size += s.free[i+1].size
+		s.free = append(s.free[:i+1], s.free[i+2:]...)
+	}
+	if i > 0 && s.free[i-1].offset+s.free[i-1].size == s.free[i].offset {
+		s.free[i-1].size += s.free[i].size
+		s.free = append(s.free[:i], s.free[i+1:]...)
+	}
+
+	if last := s.free[len(s.free)-1]; last.offset+last.size == s.end {
+		s.free = s.free[:len(s.free)-1]
+		s.end = last.offset
+		if err := s.file.Truncate(s.end); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// reset discards all spilled ranges and closes the spill file.
+func (s *spill) reset() {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	s.ranges = nil
+	s.free = nil
+	s.end = 0
+}
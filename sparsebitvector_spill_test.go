@@ -0,0 +1,136 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestSpill(t *testing.T) {
+	vec := New()
+	vec.SetMemoryBudget(10 * int(unsafe.Sizeof(element{})))
+	expected := []KeyType{}
+	for i := KeyType(0); i < 1000; i++ {
+		vec.Set(i * 3 * ElementSize)
+		expected = append(expected, i*3*ElementSize)
+	}
+
+	s := vec.Stats()
+	if s.Elements > 10 || s.SpilledBlocks+s.Elements != 1000 || s.Spills == 0 || s.SpillBytes == 0 {
+		t.Error("unexpected stats", s)
+	}
+	if vec.Count() != 1000 {
+		t.Error("incorrect count", vec.Count())
+	}
+
+	result := []KeyType{}
+	for i := range vec.Iterate() {
+		result = append(result, i)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Error("incorrect iteration", result)
+	}
+
+	if !vec.Test(3*ElementSize) || vec.Test(3*ElementSize+1) || !vec.Test(999*3*ElementSize) {
+		t.Error("incorrect contents")
+	}
+	if s := vec.Stats(); s.PageIns == 0 || s.Elements > 10 {
+		t.Error("unexpected stats", s)
+	}
+
+	if i, ok := vec.NextSet(3*ElementSize + 1); !ok || i != 6*ElementSize {
+		t.Error("incorrect next", i)
+	}
+	if i, ok := vec.PrevSet(500*3*ElementSize - 1); !ok || i != 499*3*ElementSize {
+		t.Error("incorrect prev", i)
+	}
+
+	vec.Unset(6 * ElementSize)
+	vec.Set(6*ElementSize + 1)
+	other := New(expected...)
+	other.Unset(6 * ElementSize)
+	other.Set(6*ElementSize + 1)
+	if !vec.Equals(other) || !other.Equals(vec) {
+		t.Error("vec should equal other")
+	}
+	if s := vec.Stats(); s.Elements > 10 {
+		t.Error("unexpected stats", s)
+	}
+
+	if vec.IntersectWith(New(0, 6*ElementSize+1, 2997*ElementSize)); vec.String() != "[0 769 383616]" {
+		t.Error("incorrect intersection", vec)
+	}
+
+	vec.ResetStats()
+	if s := vec.Stats(); s.Spills != 0 || s.PageIns != 0 {
+		t.Error("unexpected stats", s)
+	}
+
+	vec.SetMemoryBudget(0)
+	if s := vec.Stats(); s.SpilledBlocks != 0 || s.Elements != 3 {
+		t.Error("unexpected stats", s)
+	}
+}
+
+func TestSpillReadOnly(t *testing.T) {
+	vec := New()
+	vec.SetMemoryBudget(10 * int(unsafe.Sizeof(element{})))
+	for i := KeyType(0); i < 1000; i++ {
+		vec.Set(i * 3 * ElementSize)
+	}
+	other := New(0, 3*ElementSize, 1, 999*3*ElementSize)
+	before := vec.Stats()
+
+	if n := vec.IntersectionSize(other); n != 3 {
+		t.Error("incorrect intersection size", n)
+	}
+	if !vec.Contains(New(0, 999*3*ElementSize)) || vec.Equals(other) || Cmp(vec, other) != 1 {
+		t.Error("incorrect comparison")
+	}
+	if other.UnionWith(vec); other.Count() != 1001 {
+		t.Error("incorrect union", other.Count())
+	}
+	if after := vec.Stats(); after.Spills != before.Spills || after.PageIns != before.PageIns || after.SpilledBlocks != before.SpilledBlocks {
+		t.Error("read-only operations paged", before, after)
+	}
+}
+
+func TestSpillReuse(t *testing.T) {
+	vec := New()
+	vec.EnableSummary()
+	vec.SetMemoryBudget(100 * int(unsafe.Sizeof(element{})))
+	for i := KeyType(0); i < 1000; i++ {
+		vec.Set(i * 3 * ElementSize)
+	}
+	if s := vec.Stats(); s.HeapBytes > 100*int(unsafe.Sizeof(element{}))+int(unsafe.Sizeof(*vec))+s.ArrayKeys {
+		t.Error("summary exceeds budget", s)
+	}
+
+	// strided accesses page ranges in and out repeatedly
+	for round := 0; round < 20; round++ {
+		for i := KeyType(round); i < 1000; i += 97 {
+			vec.Test(i * 3 * ElementSize)
+		}
+	}
+	s := vec.Stats()
+	if s.PageIns < 100 || s.SpillBytes > int64(2*s.SpilledBlocks*blockbytes) {
+		t.Error("spill file was not reused", s)
+	}
+	if vec.Count() != 1000 || !vec.Test(500*3*ElementSize) {
+		t.Error("incorrect contents", vec.Count())
+	}
+
+	if err := vec.Close(); err != nil {
+		t.Error(err)
+	}
+	if s := vec.Stats(); s.SpilledBlocks != 0 || s.SpillBytes != 0 || s.Elements != 1000 {
+		t.Error("unexpected stats", s)
+	}
+	if err := vec.Close(); err != nil || !vec.Test(3*ElementSize) {
+		t.Error("vec unusable after Close", err)
+	}
+}
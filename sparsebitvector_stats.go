@@ -0,0 +1,164 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"encoding/json"
+	"math/bits"
+	"sync"
+	"unsafe"
+)
+
+// densitybuckets is the number of power of two buckets needed for ElementSize bits.
+const densitybuckets = 8
+
+// Stats describes the memory use and internal structure of a SparseBitVector.
+type Stats struct {
+	Elements    int     // blocks stored as FiniteBitVectors
+	ArrayBlocks int     // blocks stored in array containers
+	ArrayKeys   int     // true bits stored in array containers
+	Bits        int     // true bits
+	ElementBits int     // true bits stored in elements
+	AverageBits float64 // true bits per element
+
+	// Density[i] counts elements with between 2^i and 2^(i+1)-1 true bits.
+	Density [densitybuckets]int
+
+	HeapBytes  int     // estimated heap usage
+	LongestGap KeyType // largest difference between consecutive element indices
+	SearchHops int     // elements walked by searches and mutating operations since the last ResetStats
+
+	SpilledRanges int   // ranges of elements held in the spill file
+	SpilledBlocks int   // elements held in the spill file
+	SpillBytes    int64 // size of the spill file
+	Spills        int   // ranges spilled since the last ResetStats
+	PageIns       int   // ranges paged in since the last ResetStats
+}
+
+// Stats returns the current structure of sbv.
+func (sbv *SparseBitVector) Stats() Stats {
+	stats := Stats{
+		Bits:       sbv.count,
+		HeapBytes:  int(unsafe.Sizeof(*sbv)),
+		SearchHops: sbv.hops,
+	}
+	for e := sbv.start; e != nil; e = e.next {
+		stats.Elements++
+		stats.ElementBits += e.Count()
+		if n := e.Count(); n > 0 {
+			stats.Density[bits.Len(uint(n))-1]++
+		}
+		if e.prev != nil && e.index-e.prev.index > stats.LongestGap {
+			stats.LongestGap = e.index - e.prev.index
+		}
+	}
+	keys, blocks, bytes := sbv.arrayStats()
+	stats.ArrayKeys, stats.ArrayBlocks = keys, blocks
+	stats.HeapBytes += bytes
+	if sbv.spill != nil {
+		stats.SpilledRanges = len(sbv.spill.ranges)
+		for _, r := range sbv.spill.ranges {
+			stats.SpilledBlocks += r.blocks
+		}
+		stats.SpillBytes = sbv.spill.end
+		stats.Spills = sbv.spill.spills
+		stats.PageIns = sbv.spill.pageIns
+	}
+	if stats.Elements > 0 {
+		stats.AverageBits = float64(stats.ElementBits) / float64(stats.Elements)
+	}
+
+	stats.HeapBytes += stats.Elements * int(unsafe.Sizeof(element{}))
+	if sbv.summary != nil {
+		stats.HeapBytes += sbv.summary.bytes()
+	}
+	return stats
+}
+
+// ResetStats resets the cumulative counters reported by Stats.
+func (sbv *SparseBitVector) ResetStats() {
+	sbv.hops = 0
+	if sbv.spill != nil {
+		sbv.spill.spills = 0
+		sbv.spill.pageIns = 0
+	}
+}
+
+// add accumulates stats2 into stats.
+func (stats *Stats) add(stats2 Stats) {
+	stats.Elements += stats2.Elements
+	stats.ArrayBlocks += stats2.ArrayBlocks
+	stats.ArrayKeys += stats2.ArrayKeys
+	stats.Bits += stats2.Bits
+	stats.ElementBits += stats2.ElementBits
+	for i := range stats.Density {
+		stats.Density[i] += stats2.Density[i]
+	}
+	stats.HeapBytes += stats2.HeapBytes
+	if stats2.LongestGap > stats.LongestGap {
+		stats.LongestGap = stats2.LongestGap
+	}
+	stats.SearchHops += stats2.SearchHops
+	stats.SpilledRanges += stats2.SpilledRanges
+	stats.SpilledBlocks += stats2.SpilledBlocks
+	stats.SpillBytes += stats2.SpillBytes
+	stats.Spills += stats2.Spills
+	stats.PageIns += stats2.PageIns
+	if stats.Elements > 0 {
+		stats.AverageBits = float64(stats.ElementBits) / float64(stats.Elements)
+	}
+}
+
+// Collector aggregates Stats snapshots of SparseBitVectors under unique names.
+// It implements expvar.Var so the totals can be published with expvar.Publish.
+// Since a SparseBitVector is not safe for concurrent use, the Collector never
+// reads vectors itself: their owners call Update from the goroutine which
+// modifies each vector, and the totals reflect the most recent updates.
+// The Collector does not keep vectors alive.
+type Collector struct {
+	mutex sync.Mutex
+	stats map[string]Stats
+}
+
+// NewCollector creates an empty Collector.
+func NewCollector() *Collector {
+	return &Collector{stats: make(map[string]Stats)}
+}
+
+// Update replaces the snapshot stored under name with the current Stats of sbv.
+func (c *Collector) Update(name string, sbv *SparseBitVector) {
+	stats := sbv.Stats()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stats[name] = stats
+}
+
+// Remove removes the snapshot stored under name from the totals.
+func (c *Collector) Remove(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.stats, name)
+}
+
+// Stats returns the sum of the stored snapshots.
+// LongestGap is the largest of any snapshot.
+func (c *Collector) Stats() Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	total := Stats{}
+	for _, stats := range c.stats {
+		total.add(stats)
+	}
+	return total
+}
+
+// String returns the totals as JSON.
+func (c *Collector) String() string {
+	result, err := json.Marshal(c.Stats())
+	if err != nil {
+		panic(err)
+	}
+	return string(result)
+}
@@ -0,0 +1,135 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	vec := New()
+	if s := vec.Stats(); s.Elements != 0 || s.Bits != 0 || s.AverageBits != 0 || s.HeapBytes == 0 {
+		t.Error("unexpected stats", s)
+	}
+
+	vec = New(0, 1, 2, 3, 128, 10*ElementSize)
+	s := vec.Stats()
+	if s.Elements != 3 || s.Bits != 6 || s.AverageBits != 2 {
+		t.Error("unexpected counts", s)
+	}
+	if s.Density != [densitybuckets]int{2, 0, 1} {
+		t.Error("unexpected density", s.Density)
+	}
+	if s.LongestGap != 9 {
+		t.Error("unexpected gap", s.LongestGap)
+	}
+	if s.HeapBytes <= New(0).Stats().HeapBytes {
+		t.Error("unexpected heap bytes", s.HeapBytes)
+	}
+
+	vec.Test(0)
+	vec.ResetStats()
+	vec.Test(10 * ElementSize)
+	if s := vec.Stats(); s.SearchHops != 2 {
+		t.Error("unexpected hops", s.SearchHops)
+	}
+	vec.ResetStats()
+	if s := vec.Stats(); s.SearchHops != 0 {
+		t.Error("unexpected hops", s.SearchHops)
+	}
+}
+
+// TestConcurrentReaders is meant to be run with -race.
+func TestConcurrentReaders(t *testing.T) {
+	vecs := []*SparseBitVector{New(), NewAdaptive(4), New()}
+	vecs[2].EnableSummary()
+	for key := KeyType(0); key < 100000; key += 37 {
+		for i, vec := range vecs {
+			vec.Set(key * KeyType(i+1))
+		}
+	}
+	vecs[0].ResetStats()
+
+	wg := sync.WaitGroup{}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, a := range vecs {
+				for _, b := range vecs {
+					a.Equals(b)
+					a.Contains(b)
+					a.IntersectionSize(b)
+					a.Jaccard(b)
+					Cmp(a, b)
+					IntersectAll(a, b)
+					New().SetToDifference(a, b)
+					New(1, 74).UnionWith(a)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if s := vecs[0].Stats(); s.SearchHops != 0 {
+		t.Error("read-only operations counted hops", s.SearchHops)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	var _ expvar.Var = c
+
+	vec1 := New(0, 1, 1000)
+	vec2 := NewAdaptive(2, 5, 1<<20)
+	c.Update("vec1", vec1)
+	c.Update("vec2", New())
+	c.Update("vec2", vec2)
+
+	s := c.Stats()
+	if s.Elements != 2 || s.ArrayKeys != 2 || s.Bits != 5 || s.AverageBits != 1.5 {
+		t.Error("unexpected totals", s)
+	}
+
+	decoded := Stats{}
+	if err := json.Unmarshal([]byte(c.String()), &decoded); err != nil || decoded != s {
+		t.Error("unexpected json", c.String(), err)
+	}
+
+	c.Remove("vec1")
+	if s := c.Stats(); s.Elements != 0 || s.Bits != 2 {
+		t.Error("unexpected totals", s)
+	}
+
+	// snapshots are unaffected by later modifications
+	vec2.Set(6)
+	if s := c.Stats(); s.Bits != 2 {
+		t.Error("unexpected totals", s)
+	}
+}
+
+func TestCollectorConcurrent(t *testing.T) {
+	c := NewCollector()
+	done := make(chan bool)
+	go func() {
+		vec := New()
+		for i := KeyType(0); i < 1000; i++ {
+			vec.Set(i * 1000)
+			c.Update("vec", vec)
+		}
+		done <- true
+	}()
+	for i := 0; i < 100; i++ {
+		if c.String() == "" {
+			t.Error("empty json")
+		}
+	}
+	<-done
+	if s := c.Stats(); s.Bits != 1000 {
+		t.Error("unexpected totals", s)
+	}
+}
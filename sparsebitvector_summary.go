@@ -0,0 +1,172 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math/bits"
+	"sort"
+	"unsafe"
+)
+
+// summarybits is the number of element indices covered by a summary chunk.
+const summarybits = bitsperword * bitsperword
+
+// summaryChunk is a two-level bitmap over summarybits consecutive element indices.
+// Bit j of top is set iff words[j] is non-zero, and bit b of words[j] is set
+// iff the chunk holds the element at position j*bitsperword+b.
+type summaryChunk struct {
+	top      uint64
+	words    [bitsperword]uint64
+	elements []*element // one per set bit, in ascending order
+}
+
+// summary is a hierarchical bitmap over element indices.
+// A sorted directory of chunk keys leads to the chunk covering an index,
+// and a lookup within a chunk takes a couple of word operations.
+type summary struct {
+	keys   []KeyType
+	chunks map[KeyType]*summaryChunk
+}
+
+func newSummary() *summary {
+	return &summary{chunks: make(map[KeyType]*summaryChunk)}
+}
+
+// bytes returns the approximate heap usage of s.
+func (s *summary) bytes() int {
+	// approximate each directory entry as its key, map entry and chunk
+	entry := int(2*unsafe.Sizeof(KeyType(0)) + unsafe.Sizeof(uintptr(0)) + unsafe.Sizeof(summaryChunk{}))
+	result := len(s.keys) * entry
+	for _, c := range s.chunks {
+		result += cap(c.elements) * int(unsafe.Sizeof(uintptr(0)))
+	}
+	return result
+}
+
+// EnableSummary maintains a hierarchical summary of sbv's elements,
+// which lets searches skip empty regions of the keyspace without
+// walking the elements in between.
+func (sbv *SparseBitVector) EnableSummary() {
+	sbv.summary = newSummary()
+	for e := sbv.start; e != nil; e = e.next {
+		sbv.summary.add(e)
+	}
+}
+
+// rank returns the position of pos's element among the chunk's elements.
+func (c *summaryChunk) rank(pos uint) int {
+	r := 0
+	for top := c.top & (1<<(pos/bitsperword) - 1); top != 0; top &= top - 1 {
+		r += bits.OnesCount64(c.words[bits.TrailingZeros64(top)])
+	}
+	return r + bits.OnesCount64(c.words[pos/bitsperword]&(1<<(pos%bitsperword)-1))
+}
+
+func (c *summaryChunk) test(pos uint) bool {
+	return c.words[pos/bitsperword]>>(pos%bitsperword)&1 != 0
+}
+
+// next returns the first set position at or after pos.
+func (c *summaryChunk) next(pos uint) (uint, bool) {
+	w, b := pos/bitsperword, pos%bitsperword
+	if word := c.words[w] >> b << b; word != 0 {
+		return w*bitsperword + uint(bits.TrailingZeros64(word)), true
+	}
+	if top := c.top >> (w + 1) << (w + 1); top != 0 {
+		w = uint(bits.TrailingZeros64(top))
+		return w*bitsperword + uint(bits.TrailingZeros64(c.words[w])), true
+	}
+	return 0, false
+}
+
+// prev returns the last set position at or before pos.
+func (c *summaryChunk) prev(pos uint) (uint, bool) {
+	w, b := pos/bitsperword, pos%bitsperword
+	if word := c.words[w] << (bitsperword - 1 - b) >> (bitsperword - 1 - b); word != 0 {
+		return w*bitsperword + bitsperword - 1 - uint(bits.LeadingZeros64(word)), true
+	}
+	if top := c.top << (bitsperword - w) >> (bitsperword - w); top != 0 {
+		w = bitsperword - 1 - uint(bits.LeadingZeros64(top))
+		return w*bitsperword + bitsperword - 1 - uint(bits.LeadingZeros64(c.words[w])), true
+	}
+	return 0, false
+}
+
+func (s *summary) add(e *element) {
+	key, pos := e.index/summarybits, uint(e.index%summarybits)
+	c, ok := s.chunks[key]
+	if !ok {
+		c = &summaryChunk{}
+		s.chunks[key] = c
+		i := sort.Search(len(s.keys), func(i int) bool { return s.keys[i] > key })
+		s.keys = append(s.keys, 0)
+		copy(s.keys[i+1:], s.keys[i:])
+		s.keys[i] = key
+	}
+
+	// another element may still hold e's index while it is being moved
+	r := c.rank(pos)
+	if c.test(pos) {
+		c.elements[r] = e
+		return
+	}
+	c.words[pos/bitsperword] |= 1 << (pos % bitsperword)
+	c.top |= 1 << (pos / bitsperword)
+	c.elements = append(c.elements, nil)
+	copy(c.elements[r+1:], c.elements[r:])
+	c.elements[r] = e
+}
+
+func (s *summary) remove(e *element) {
+	key, pos := e.index/summarybits, uint(e.index%summarybits)
+	c, ok := s.chunks[key]
+	if !ok || !c.test(pos) {
+		return
+	}
+	// another element may have been moved to e's index
+	r := c.rank(pos)
+	if c.elements[r] != e {
+		return
+	}
+	c.elements = append(c.elements[:r], c.elements[r+1:]...)
+	if c.words[pos/bitsperword] &^= 1 << (pos % bitsperword); c.words[pos/bitsperword] == 0 {
+		c.top &^= 1 << (pos / bitsperword)
+	}
+	if c.top == 0 {
+		delete(s.chunks, key)
+		i := sort.Search(len(s.keys), func(i int) bool { return s.keys[i] >= key })
+		s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	}
+}
+
+// successor returns the first element with an index of at least index, or nil.
+func (s *summary) successor(index KeyType) *element {
+	key := index / summarybits
+	if c, ok := s.chunks[key]; ok {
+		if pos, ok := c.next(uint(index % summarybits)); ok {
+			return c.elements[c.rank(pos)]
+		}
+	}
+	// chunks are never empty, so the next one holds the successor
+	if i := sort.Search(len(s.keys), func(i int) bool { return s.keys[i] > key }); i < len(s.keys) {
+		return s.chunks[s.keys[i]].elements[0]
+	}
+	return nil
+}
+
+// predecessor returns the last element with an index of at most index, or nil.
+func (s *summary) predecessor(index KeyType) *element {
+	key := index / summarybits
+	if c, ok := s.chunks[key]; ok {
+		if pos, ok := c.prev(uint(index % summarybits)); ok {
+			return c.elements[c.rank(pos)]
+		}
+	}
+	if i := sort.Search(len(s.keys), func(i int) bool { return s.keys[i] >= key }); i > 0 {
+		elements := s.chunks[s.keys[i-1]].elements
+		return elements[len(elements)-1]
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNextAndPrevSet(t *testing.T) {
+	for _, vec := range []*SparseBitVector{New(), NewAdaptive(2)} {
+		if _, ok := vec.NextSet(0); ok {
+			t.Error("unexpected next", vec)
+		}
+		if _, ok := vec.PrevSet(1000); ok {
+			t.Error("unexpected prev", vec)
+		}
+
+		vec.Set(5)
+		vec.Set(6)
+		vec.Set(1000)
+		vec.Set(1 << 62)
+		if i, ok := vec.NextSet(0); !ok || i != 5 {
+			t.Error("incorrect next", i, vec)
+		}
+		if i, ok := vec.NextSet(6); !ok || i != 6 {
+			t.Error("incorrect next", i, vec)
+		}
+		if i, ok := vec.NextSet(7); !ok || i != 1000 {
+			t.Error("incorrect next", i, vec)
+		}
+		if i, ok := vec.NextSet(1001); !ok || i != 1<<62 {
+			t.Error("incorrect next", i, vec)
+		}
+		if _, ok := vec.NextSet(1<<62 + 1); ok {
+			t.Error("unexpected next", vec)
+		}
+		if _, ok := vec.PrevSet(4); ok {
+			t.Error("unexpected prev", vec)
+		}
+		if i, ok := vec.PrevSet(999); !ok || i != 6 {
+			t.Error("incorrect prev", i, vec)
+		}
+		if i, ok := vec.PrevSet(1 << 62); !ok || i != 1<<62 {
+			t.Error("incorrect prev", i, vec)
+		}
+		if i, ok := vec.PrevSet(1<<62 - 1); !ok || i != 1000 {
+			t.Error("incorrect prev", i, vec)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	vec := New(0, 1000, 1<<40, 1<<62)
+	vec.EnableSummary()
+
+	if e := vec.summary.successor(1); e == nil || e.index != 1000/ElementSize {
+		t.Error("incorrect successor", e)
+	}
+	if e := vec.summary.successor(1<<40/ElementSize + 1); e == nil || e.index != 1<<62/ElementSize {
+		t.Error("incorrect successor", e)
+	}
+	if e := vec.summary.successor(1<<62/ElementSize + 1); e != nil {
+		t.Error("unexpected successor", e)
+	}
+	if e := vec.summary.predecessor(1<<62/ElementSize - 1); e == nil || e.index != 1<<40/ElementSize {
+		t.Error("incorrect predecessor", e)
+	}
+	if e := vec.summary.predecessor(6); e == nil || e.index != 0 {
+		t.Error("incorrect predecessor", e)
+	}
+
+	vec.Unset(1 << 40)
+	if e := vec.summary.predecessor(1<<62/ElementSize - 1); e == nil || e.index != 1000/ElementSize {
+		t.Error("incorrect predecessor", e)
+	}
+
+	vec.Set(1 << 50)
+	vec.Set(1<<50 + ElementSize)
+	if s := vec.String(); s != "[0 1000 1125899906842624 1125899906842752 4611686018427387904]" {
+		t.Error("incorrect order", s)
+	}
+	if i := vec.IntersectionSize(New(1000, 1<<50, 1<<62)); i != 3 {
+		t.Error("incorrect intersection size", i)
+	}
+	if vec.IntersectWith(New(1<<50, 1<<62)); vec.String() != "[1125899906842624 4611686018427387904]" {
+		t.Error("incorrect intersection", vec)
+	}
+	if e := vec.summary.successor(0); e == nil || e.index != 1<<50/ElementSize {
+		t.Error("incorrect successor", e)
+	}
+
+	vec.Clear()
+	if e := vec.summary.successor(0); e != nil {
+		t.Error("unexpected successor", e)
+	}
+}
+
+func TestSummaryRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	vec := New()
+	vec.EnableSummary()
+	for i := 0; i < 5000; i++ {
+		// indices clustered around a few chunk boundaries
+		index := KeyType(r.Intn(4))*summarybits + KeyType(r.Intn(200)) - 100 + summarybits
+		if r.Intn(3) == 0 {
+			vec.Unset(index * ElementSize)
+		} else {
+			vec.Set(index * ElementSize)
+		}
+
+		probe := KeyType(r.Intn(6)) * summarybits / 2
+		probe += KeyType(r.Intn(300))
+		var succ, pred *element
+		for e := vec.start; e != nil; e = e.next {
+			if e.index >= probe && succ == nil {
+				succ = e
+			}
+			if e.index <= probe {
+				pred = e
+			}
+		}
+		if e := vec.summary.successor(probe); e != succ {
+			t.Error("incorrect successor", probe, e, succ)
+		}
+		if e := vec.summary.predecessor(probe); e != pred {
+			t.Error("incorrect predecessor", probe, e, pred)
+		}
+	}
+}
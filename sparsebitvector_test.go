@@ -63,6 +63,22 @@ func TestTrivialOperation(t *testing.T) {
 	}
 }
 
+func TestNewRange(t *testing.T) {
+	if vec := NewRange(3, 7); vec.String() != "[3 4 5 6 7]" {
+		t.Error("incorrect range", vec)
+	}
+	if vec := NewRange(7, 3); vec.Count() != 0 {
+		t.Error("unexpected range", vec)
+	}
+	vec := NewRange(ElementSize-1, 3*ElementSize)
+	if vec.Count() != 2*ElementSize+2 || !vec.Equals(UnionAll(New(ElementSize-1, 3*ElementSize), NewRange(ElementSize, 3*ElementSize-1))) {
+		t.Error("incorrect range", vec.Count())
+	}
+	if vec := NewRange(^KeyType(0)-1, ^KeyType(0)); vec.Count() != 2 || !vec.Test(^KeyType(0)) {
+		t.Error("incorrect range", vec)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	vec := New(0, 128, 1000000000)
 	if vec.start.next.next.next != nil {
@@ -87,6 +103,21 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestSearchOrder(t *testing.T) {
+	vec := New(ElementSize, 5*ElementSize, 9*ElementSize)
+	vec.Test(ElementSize)
+	vec.Set(3 * ElementSize)
+	if s := vec.String(); s != "[128 384 640 1152]" {
+		t.Error("incorrect order", s)
+	}
+
+	vec.Test(9 * ElementSize)
+	vec.Set(7 * ElementSize)
+	if s := vec.String(); s != "[128 384 640 896 1152]" {
+		t.Error("incorrect order", s)
+	}
+}
+
 func TestEquals(t *testing.T) {
 	vec1 := New()
 	vec2 := New()
@@ -297,6 +328,29 @@ func TestIntersectWithComplement(t *testing.T) {
 	}
 }
 
+func TestSymmetricDifferenceWith(t *testing.T) {
+	vec1 := New(0, 63, 1000000)
+	vec2 := New(0, 127, 128, 1000000)
+	if !vec1.SymmetricDifferenceWith(vec2) || vec1.String() != "[63 127 128]" || vec1.Count() != 3 {
+		t.Error("incorrect symmetric difference", vec1)
+	}
+	if vec1.SymmetricDifferenceWith(New()) || vec1.Hash() != New(63, 127, 128).Hash() {
+		t.Error("incorrect symmetric difference", vec1)
+	}
+	if !vec1.SymmetricDifferenceWith(vec1) || vec1.Count() != 0 {
+		t.Error("incorrect symmetric difference with itself", vec1)
+	}
+
+	// array blocks are merged and promoted in place
+	vec3 := NewAdaptive(2, 0, 1000)
+	if vec3.SymmetricDifferenceWith(New(1, 1000, 5000)); vec3.String() != "[0 1 5000]" {
+		t.Error("incorrect symmetric difference", vec3)
+	}
+	if s := vec3.Stats(); s.Elements != 1 || s.ArrayKeys != 1 || vec3.Count() != 3 {
+		t.Error("unexpected stats", s)
+	}
+}
+
 func TestSparseBitVectorString(t *testing.T) {
 	vec := New()
 	if s := vec.String(); s != "[]" {
@@ -338,4 +392,76 @@ func TestIteration(t *testing.T) {
 	if result := test(); !reflect.DeepEqual(result, []KeyType{0, 5, 65, 1000000000}) {
 		t.Error("incorrect result", result, vec)
 	}
+
+	indices := []KeyType{}
+	vec.Blocks(func(index KeyType, block FiniteBitVector) {
+		indices = append(indices, index)
+		block.Clear()
+	})
+	if !reflect.DeepEqual(indices, []KeyType{0, 1000000000 / ElementSize}) || vec.Count() != 4 || !vec.Test(65) {
+		t.Error("incorrect blocks", indices, vec)
+	}
+}
+
+func TestChangeReporting(t *testing.T) {
+	vec := New()
+	if !vec.Set(5) || vec.Set(5) {
+		t.Error("incorrect set change", vec)
+	}
+	if !vec.Unset(5) || vec.Unset(5) || vec.Unset(1000) {
+		t.Error("incorrect unset change", vec)
+	}
+
+	vec = New(0, 63, 1000000)
+	if changed, n := vec.UnionWith(New(0, 127, 128, 1000000)); !changed || n != 2 {
+		t.Error("incorrect union change", changed, n)
+	}
+	if changed, n := vec.UnionWith(New(0, 128)); changed || n != 0 {
+		t.Error("incorrect union change", changed, n)
+	}
+	if changed, n := vec.IntersectWithComplement(New(63, 64, 128)); !changed || n != 2 {
+		t.Error("incorrect complement intersection change", changed, n)
+	}
+	if changed, n := vec.IntersectWithComplement(New(63)); changed || n != 0 {
+		t.Error("incorrect complement intersection change", changed, n)
+	}
+	if changed, n := vec.IntersectWith(New(0, 127)); !changed || n != 1 || vec.String() != "[0 127]" {
+		t.Error("incorrect intersection change", changed, n, vec)
+	}
+	if changed, n := vec.IntersectWith(vec); changed || n != 0 {
+		t.Error("incorrect intersection change", changed, n)
+	}
+	if changed, n := vec.Clear(); !changed || n != 2 {
+		t.Error("incorrect clear change", changed, n)
+	}
+	if changed, n := vec.Clear(); changed || n != 0 {
+		t.Error("incorrect clear change", changed, n)
+	}
+}
+
+func TestChangeCallbacks(t *testing.T) {
+	keys := []KeyType{}
+	record := func(key KeyType) {
+		keys = append(keys, key)
+	}
+
+	vec := New(0, 63, 1000000)
+	if changed, n := vec.UnionWithFunc(New(0, 127, 128, 1000000), record); !changed || n != 2 || !reflect.DeepEqual(keys, []KeyType{127, 128}) {
+		t.Error("incorrect union callbacks", changed, n, keys)
+	}
+
+	keys = nil
+	if changed, n := vec.IntersectWithComplementFunc(New(0, 127, 1000000), record); !changed || n != 3 || !reflect.DeepEqual(keys, []KeyType{0, 127, 1000000}) {
+		t.Error("incorrect complement intersection callbacks", changed, n, keys)
+	}
+
+	keys = nil
+	if changed, n := vec.IntersectWithFunc(New(128, 1000000), record); !changed || n != 1 || !reflect.DeepEqual(keys, []KeyType{63}) {
+		t.Error("incorrect intersection callbacks", changed, n, keys)
+	}
+
+	// elements emptied by an intersection are removed
+	if !vec.Equals(New(128)) || vec.start.next != nil {
+		t.Error("incorrect result", vec)
+	}
 }
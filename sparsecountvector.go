@@ -0,0 +1,180 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// countbits is the number of bits in a SparseCountVector's counters.
+const countbits = 8
+
+// MaxCount is the value at which a SparseCountVector's counters saturate.
+const MaxCount = 1<<countbits - 1
+
+// SparseCountVector is a sparse multiset holding a saturating counter per key.
+// The counters are bit-sliced: plane i is a SparseBitVector of the keys whose
+// count has bit i set, so a key only occupies elements in the planes it needs.
+type SparseCountVector struct {
+	planes [countbits]*SparseBitVector
+}
+
+// NewSparseCountVector creates an empty SparseCountVector.
+func NewSparseCountVector() *SparseCountVector {
+	scv := &SparseCountVector{}
+	for i := range scv.planes {
+		scv.planes[i] = New()
+	}
+	return scv
+}
+
+// NewSparseCountVectorOf creates a SparseCountVector with a count of 1 for each true bit of sbv.
+func NewSparseCountVectorOf(sbv *SparseBitVector) *SparseCountVector {
+	scv := NewSparseCountVector()
+	scv.planes[0] = sbv.clone()
+	return scv
+}
+
+// Get returns the count of key.
+func (scv *SparseCountVector) Get(key KeyType) int {
+	count := 0
+	for i, plane := range scv.planes {
+		if plane.Test(key) {
+			count |= 1 << uint(i)
+		}
+	}
+	return count
+}
+
+// Inc increments the count of key, unless it is MaxCount.
+// It returns true iff the count changed.
+func (scv *SparseCountVector) Inc(key KeyType) bool {
+	if scv.Get(key) == MaxCount {
+		return false
+	}
+	// carry into the planes until a bit was clear
+	for _, plane := range scv.planes {
+		if plane.Set(key) {
+			break
+		}
+		plane.Unset(key)
+	}
+	return true
+}
+
+// Dec decrements the count of key, unless it is 0.
+// It returns true iff the count changed.
+func (scv *SparseCountVector) Dec(key KeyType) bool {
+	if scv.Get(key) == 0 {
+		return false
+	}
+	// borrow from the planes until a bit was set
+	for _, plane := range scv.planes {
+		if plane.Unset(key) {
+			break
+		}
+		plane.Set(key)
+	}
+	return true
+}
+
+// Keys returns the keys with a non-zero count.
+func (scv *SparseCountVector) Keys() *SparseBitVector {
+	return UnionAll(scv.planes[:]...)
+}
+
+// Equals returns true iff scv and scv2 hold equal counts.
+func (scv *SparseCountVector) Equals(scv2 *SparseCountVector) bool {
+	for i, plane := range scv.planes {
+		if !plane.Equals(scv2.planes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Add adds the counts of scv2 to scv, saturating at MaxCount.
+// It returns true iff scv changed.
+func (scv *SparseCountVector) Add(scv2 *SparseCountVector) bool {
+	// ripple carry addition from the least significant plane
+	sum := [countbits]*SparseBitVector{}
+	carry := New()
+	for i := range sum {
+		a, b := scv.planes[i], scv2.planes[i]
+		half := a.clone()
+		half.SymmetricDifferenceWith(b)
+		next := IntersectAll(a, b)
+		next.UnionWith(IntersectAll(carry, half))
+		half.SymmetricDifferenceWith(carry)
+		sum[i], carry = half, next
+	}
+
+	// counters which overflowed saturate
+	changed := false
+	for i, plane := range sum {
+		plane.UnionWith(carry)
+		changed = changed || !plane.Equals(scv.planes[i])
+	}
+	scv.planes = sum
+	return changed
+}
+
+// Min sets each count of scv to the minimum of itself and the count in scv2.
+// It returns true iff scv changed.
+func (scv *SparseCountVector) Min(scv2 *SparseCountVector) bool {
+	// compare from the most significant plane: where the counts first
+	// differ, scv2's is smaller iff scv has the bit
+	smaller := New()
+	undecided := scv.Keys()
+	for i := countbits - 1; i >= 0; i-- {
+		differ := scv.planes[i].clone()
+		differ.SymmetricDifferenceWith(scv2.planes[i])
+		differ.IntersectWith(undecided)
+		smaller.UnionWith(IntersectAll(differ, scv.planes[i]))
+		undecided.IntersectWithComplement(differ)
+	}
+	if smaller.Count() == 0 {
+		return false
+	}
+
+	for i, plane := range scv.planes {
+		plane.IntersectWithComplement(smaller)
+		plane.UnionWith(IntersectAll(scv2.planes[i], smaller))
+	}
+	return true
+}
+
+// AtLeast returns a new SparseBitVector of the keys with a count of at least n.
+func (scv *SparseCountVector) AtLeast(n int) *SparseBitVector {
+	if n <= 1 {
+		return scv.Keys()
+	}
+	if n > MaxCount {
+		return New()
+	}
+
+	// compare with n from the most significant plane
+	greater, equal := New(), scv.Keys()
+	for i := countbits - 1; i >= 0; i-- {
+		if n>>uint(i)&1 == 1 {
+			equal.IntersectWith(scv.planes[i])
+		} else {
+			greater.UnionWith(IntersectAll(equal, scv.planes[i]))
+			equal.IntersectWithComplement(scv.planes[i])
+		}
+	}
+	greater.UnionWith(equal)
+	return greater
+}
+
+func (scv *SparseCountVector) String() string {
+	result := []string{}
+	keys := scv.Keys()
+	for key, ok := keys.NextSet(0); ok; key, ok = keys.NextSet(key + 1) {
+		result = append(result, fmt.Sprint(key, ":", scv.Get(key)))
+	}
+	return "{" + strings.Join(result, " ") + "}"
+}
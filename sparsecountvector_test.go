@@ -0,0 +1,167 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSparseCountVector(t *testing.T) {
+	scv := NewSparseCountVectorOf(New(1, 2, 1<<40))
+	if scv.String() != "{1:1 2:1 1099511627776:1}" {
+		t.Error("incorrect conversion", scv)
+	}
+
+	// a count of 1 only occupies the least significant plane
+	elements := 0
+	for _, plane := range scv.planes {
+		elements += plane.Stats().Elements
+	}
+	if elements != 2 {
+		t.Error("unexpected elements", elements)
+	}
+
+	for i := 0; i < 6; i++ {
+		scv.Inc(2)
+	}
+	if !scv.Dec(1) || scv.Dec(1) || scv.Dec(5) {
+		t.Error("incorrect decrement", scv)
+	}
+	if scv.String() != "{2:7 1099511627776:1}" || scv.Get(1) != 0 {
+		t.Error("incorrect counts", scv)
+	}
+
+	for i := 0; i < MaxCount; i++ {
+		scv.Inc(3)
+	}
+	if scv.Inc(3) || scv.Get(3) != MaxCount {
+		t.Error("incorrect saturation", scv.Get(3))
+	}
+	if !scv.Dec(3) || scv.Get(3) != MaxCount-1 {
+		t.Error("incorrect decrement", scv.Get(3))
+	}
+
+	if r := scv.AtLeast(7); r.String() != "[2 3]" {
+		t.Error("incorrect at least", r)
+	}
+	if r := scv.AtLeast(0); r.String() != "[2 3 1099511627776]" || scv.AtLeast(MaxCount+1).Count() != 0 {
+		t.Error("incorrect at least", r)
+	}
+}
+
+func TestSparseCountVectorMerge(t *testing.T) {
+	a, b := NewSparseCountVector(), NewSparseCountVector()
+	for i := 0; i < 5; i++ {
+		a.Inc(1)
+		b.Inc(2)
+	}
+	a.Inc(2)
+	a.Inc(2)
+	for i := 0; i < MaxCount-1; i++ {
+		a.Inc(3)
+	}
+	b.Inc(3)
+	b.Inc(3)
+
+	sum := NewSparseCountVector()
+	sum.Add(a)
+	if !sum.Add(b) || sum.String() != "{1:5 2:7 3:255}" {
+		t.Error("incorrect sum", sum)
+	}
+	if !sum.Min(b) || sum.String() != "{2:5 3:2}" {
+		t.Error("incorrect min", sum)
+	}
+	if sum.Min(sum) || sum.Add(NewSparseCountVector()) {
+		t.Error("unexpected change", sum)
+	}
+	if !sum.Add(sum) || sum.String() != "{2:10 3:4}" {
+		t.Error("incorrect doubling", sum)
+	}
+
+	// saturated counters absorb further additions without changing
+	full := NewSparseCountVector()
+	for i := 0; i < MaxCount; i++ {
+		full.Inc(3)
+	}
+	if full.Add(NewSparseCountVectorOf(New(3))) || full.Add(full) || full.String() != "{3:255}" {
+		t.Error("unexpected saturated change", full)
+	}
+}
+
+func TestSparseCountVectorMinDisjoint(t *testing.T) {
+	// counts whose set bits are disjoint
+	a, b := NewSparseCountVector(), NewSparseCountVector()
+	for i := 0; i < 4; i++ {
+		a.Inc(1)
+	}
+	for i := 0; i < 3; i++ {
+		b.Inc(1)
+	}
+	a.Inc(2)
+	a.Inc(2)
+	b.Inc(2)
+	b.Inc(1 << 40)
+	if !a.Min(b) || a.String() != "{1:3 2:1}" {
+		t.Error("incorrect min", a)
+	}
+	if !b.Min(NewSparseCountVectorOf(New(1, 2))) || b.String() != "{1:1 2:1}" {
+		t.Error("incorrect min", b)
+	}
+	if !a.Min(NewSparseCountVectorOf(New(2))) || !a.Equals(NewSparseCountVectorOf(New(2))) {
+		t.Error("incorrect min", a)
+	}
+}
+
+func TestSparseCountVectorExhaustive(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() (*SparseCountVector, map[KeyType]int) {
+		scv, ref := NewSparseCountVector(), map[KeyType]int{}
+		for i := 0; i < 2000; i++ {
+			key := KeyType(r.Intn(400))
+			if r.Intn(4) == 0 {
+				scv.Dec(key)
+				if ref[key] > 0 {
+					ref[key]--
+				}
+			} else {
+				scv.Inc(key)
+				if ref[key] < MaxCount {
+					ref[key]++
+				}
+			}
+		}
+		return scv, ref
+	}
+	a, refA := random()
+	b, refB := random()
+
+	min, sum := NewSparseCountVector(), NewSparseCountVector()
+	min.Add(a)
+	min.Min(b)
+	sum.Add(a)
+	sum.Add(b)
+	for key := KeyType(0); key < 400; key++ {
+		if a.Get(key) != refA[key] {
+			t.Error("incorrect count", key, a.Get(key), refA[key])
+		}
+		expected := refA[key]
+		if refB[key] < expected {
+			expected = refB[key]
+		}
+		if min.Get(key) != expected {
+			t.Error("incorrect min", key, min.Get(key), expected)
+		}
+		if expected = refA[key] + refB[key]; expected > MaxCount {
+			expected = MaxCount
+		}
+		if sum.Get(key) != expected {
+			t.Error("incorrect sum", key, sum.Get(key), expected)
+		}
+		if a.AtLeast(5).Test(key) != (refA[key] >= 5) {
+			t.Error("incorrect at least", key)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import "math/bits"
+
+// mapBlock holds the values of the keys of one block of a SparseMap.
+type mapBlock[V any] struct {
+	index   KeyType
+	present FiniteBitVector
+	values  []V // one per true bit of present, in ascending order
+	prev    *mapBlock[V]
+	next    *mapBlock[V]
+}
+
+// rank returns the position of bit i's value among the block's values.
+func (b *mapBlock[V]) rank(i uint) int {
+	r := 0
+	for w := 0; w < int(i/bitsperword); w++ {
+		r += bits.OnesCount64(uint64(b.present[w]))
+	}
+	mask := elementwordtype(1)<<(i%bitsperword) - 1
+	return r + bits.OnesCount64(uint64(b.present[i/bitsperword]&mask))
+}
+
+// SparseMap is an ordered map from keys to values with the same indexed block layout as SparseBitVector.
+// Each block holds a FiniteBitVector of present keys and a compact slice of their values.
+type SparseMap[V any] struct {
+	start   *mapBlock[V]
+	current *mapBlock[V]
+	last    *mapBlock[V]
+	count   int
+}
+
+// NewSparseMap creates an empty SparseMap.
+func NewSparseMap[V any]() *SparseMap[V] {
+	return &SparseMap[V]{}
+}
+
+// search returns the block for index if it exists,
+// otherwise a block adjacent to where it would be inserted.
+func (m *SparseMap[V]) search(index KeyType) *mapBlock[V] {
+	if m.current == nil {
+		if m.start == nil {
+			return nil
+		}
+		m.current = m.start
+	}
+	for m.current.index > index && m.current.prev != nil {
+		m.current = m.current.prev
+	}
+	for m.current.index < index && m.current.next != nil && m.current.next.index <= index {
+		m.current = m.current.next
+	}
+	return m.current
+}
+
+// insert returns the block for index, creating an empty one if necessary.
+func (m *SparseMap[V]) insert(index KeyType) *mapBlock[V] {
+	nearest := m.search(index)
+	if nearest != nil && nearest.index == index {
+		return nearest
+	}
+	b := &mapBlock[V]{index: index}
+	switch {
+	case nearest == nil:
+		m.start, m.last = b, b
+	case nearest.index < index:
+		b.prev, b.next = nearest, nearest.next
+	default:
+		b.prev, b.next = nearest.prev, nearest
+	}
+	if b.prev == nil {
+		m.start = b
+	} else {
+		b.prev.next = b
+	}
+	if b.next == nil {
+		m.last = b
+	} else {
+		b.next.prev = b
+	}
+	m.current = b
+	return b
+}
+
+func (m *SparseMap[V]) delete(b *mapBlock[V]) {
+	if m.start == b {
+		m.start = b.next
+	}
+	if m.last == b {
+		m.last = b.prev
+	}
+	if m.current == b {
+		m.current = b.prev
+		if m.current == nil {
+			m.current = b.next
+		}
+	}
+	if b.prev != nil {
+		b.prev.next = b.next
+	}
+	if b.next != nil {
+		b.next.prev = b.prev
+	}
+}
+
+// Len returns the number of keys in m.
+func (m *SparseMap[V]) Len() int {
+	return m.count
+}
+
+// Get returns the value of key, and false if key is absent.
+func (m *SparseMap[V]) Get(key KeyType) (V, bool) {
+	index, i := key/ElementSize, uint(key%ElementSize)
+	if b := m.search(index); b != nil && b.index == index && b.present.Test(i) {
+		return b.values[b.rank(i)], true
+	}
+	var zero V
+	return zero, false
+}
+
+// Put sets the value of key.
+// It returns true iff key was absent.
+func (m *SparseMap[V]) Put(key KeyType, value V) bool {
+	b, i := m.insert(key/ElementSize), uint(key%ElementSize)
+	r := b.rank(i)
+	if !b.present.TestAndSet(i) {
+		b.values[r] = value
+		return false
+	}
+	var zero V
+	b.values = append(b.values, zero)
+	copy(b.values[r+1:], b.values[r:])
+	b.values[r] = value
+	m.count++
+	return true
+}
+
+// Delete removes key.
+// It returns true iff key was present.
+func (m *SparseMap[V]) Delete(key KeyType) bool {
+	index, i := key/ElementSize, uint(key%ElementSize)
+	b := m.search(index)
+	if b == nil || b.index != index || !b.present.TestAndUnset(i) {
+		return false
+	}
+	r := b.rank(i)
+	copy(b.values[r:], b.values[r+1:])
+	var zero V
+	b.values[len(b.values)-1] = zero
+	b.values = b.values[:len(b.values)-1]
+	if len(b.values) == 0 {
+		m.delete(b)
+	}
+	m.count--
+	return true
+}
+
+// Clear removes all keys.
+func (m *SparseMap[V]) Clear() {
+	*m = SparseMap[V]{}
+}
+
+// Each calls fn with each key and value in ascending key order until fn returns false.
+func (m *SparseMap[V]) Each(fn func(key KeyType, value V) bool) {
+	m.Range(0, ^KeyType(0), fn)
+}
+
+// Range calls fn with each key from lo to hi inclusive and its value,
+// in ascending key order, until fn returns false.
+func (m *SparseMap[V]) Range(lo, hi KeyType, fn func(key KeyType, value V) bool) {
+	b := m.search(lo / ElementSize)
+	if b != nil && b.index < lo/ElementSize {
+		b = b.next
+	}
+	for ; b != nil && b.index <= hi/ElementSize; b = b.next {
+		r := 0
+		for i := b.present.FindNext(0); i != -1; i = b.present.FindNext(i + 1) {
+			key := b.index*ElementSize + KeyType(i)
+			if key > hi {
+				return
+			}
+			if key >= lo && !fn(key, b.values[r]) {
+				return
+			}
+			r++
+		}
+	}
+}
+
+// Keys returns a new SparseBitVector of the keys in m.
+func (m *SparseMap[V]) Keys() *SparseBitVector {
+	result := New()
+	for b := m.start; b != nil; b = b.next {
+		result.push(b.index, &b.present)
+	}
+	return result
+}
+
+// IntersectWith removes the keys of m which are not true in sbv.
+// It returns true iff m changed, along with the number of keys removed.
+func (m *SparseMap[V]) IntersectWith(sbv *SparseBitVector) (bool, int) {
+	removed := 0
+	c := sbv.cursor()
+	for b := m.start; b != nil; b = b.next {
+		c.seek(b.index)
+		keep := FiniteBitVector{}
+		if c.valid && c.index == b.index {
+			keep = b.present
+			keep.IntersectWith(c.vec())
+		}
+		if keep.Equals(&b.present) {
+			continue
+		}
+
+		// compact the values of the kept keys
+		values := b.values[:0]
+		r := 0
+		for i := b.present.FindNext(0); i != -1; i = b.present.FindNext(i + 1) {
+			if keep.Test(uint(i)) {
+				values = append(values, b.values[r])
+			}
+			r++
+		}
+		var zero V
+		for r := len(values); r < len(b.values); r++ {
+			b.values[r] = zero
+		}
+		removed += len(b.values) - len(values)
+		b.present, b.values = keep, values
+		if len(values) == 0 {
+			m.delete(b)
+		}
+	}
+	m.count -= removed
+	return removed != 0, removed
+}
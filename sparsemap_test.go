@@ -0,0 +1,122 @@
+// This file is distributed under the
+// University of Illinois Open Source License.
+// See LICENSE.TXT for details.
+
+package sparsebitvector
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSparseMap(t *testing.T) {
+	m := NewSparseMap[string]()
+	if !m.Put(1000, "c") || !m.Put(3, "b") || !m.Put(1, "a") || m.Put(3, "B") || m.Len() != 3 {
+		t.Error("unexpected put", m.Len())
+	}
+	if v, ok := m.Get(3); !ok || v != "B" {
+		t.Error("incorrect value", v, ok)
+	}
+	if _, ok := m.Get(2); ok {
+		t.Error("unexpected value")
+	}
+	if m.Keys().String() != "[1 3 1000]" {
+		t.Error("incorrect keys", m.Keys())
+	}
+
+	keys := []KeyType{}
+	values := ""
+	m.Each(func(key KeyType, value string) bool {
+		keys = append(keys, key)
+		values += value
+		return true
+	})
+	if len(keys) != 3 || keys[2] != 1000 || values != "aBc" {
+		t.Error("incorrect iteration", keys, values)
+	}
+
+	if m.Delete(2) || !m.Delete(1000) || m.Delete(1000) || m.Len() != 2 || m.Keys().String() != "[1 3]" {
+		t.Error("unexpected delete", m.Keys())
+	}
+	m.Clear()
+	if m.Len() != 0 || m.Keys().Count() != 0 {
+		t.Error("unexpected clear")
+	}
+}
+
+func TestSparseMapRange(t *testing.T) {
+	m := NewSparseMap[int]()
+	for key := KeyType(0); key < 1000; key += 7 {
+		m.Put(key, int(key))
+	}
+	sum := 0
+	m.Range(100, 300, func(key KeyType, value int) bool {
+		if key < 100 || key > 300 || int(key) != value {
+			t.Error("incorrect entry", key, value)
+		}
+		sum++
+		return true
+	})
+	if sum != 28 {
+		t.Error("incorrect range size", sum)
+	}
+
+	seen := 0
+	m.Range(0, 1000, func(key KeyType, value int) bool {
+		seen++
+		return seen < 5
+	})
+	if seen != 5 {
+		t.Error("range did not stop", seen)
+	}
+}
+
+func TestSparseMapIntersectWith(t *testing.T) {
+	m := NewSparseMap[int]()
+	for _, key := range []KeyType{1, 2, 3, 200, 201, 5000} {
+		m.Put(key, int(key))
+	}
+	if changed, removed := m.IntersectWith(New(2, 3, 201, 6000)); !changed || removed != 3 {
+		t.Error("unexpected intersection", changed, removed)
+	}
+	if changed, removed := m.IntersectWith(New(2, 3, 201)); changed || removed != 0 {
+		t.Error("unexpected intersection", changed, removed)
+	}
+	if m.Len() != 3 || m.Keys().String() != "[2 3 201]" {
+		t.Error("incorrect keys", m.Keys())
+	}
+	if v, ok := m.Get(201); !ok || v != 201 {
+		t.Error("incorrect value", v, ok)
+	}
+}
+
+func TestSparseMapRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	m := NewSparseMap[KeyType]()
+	reference := map[KeyType]KeyType{}
+	for i := 0; i < 20000; i++ {
+		key := KeyType(r.Intn(2000))
+		switch r.Intn(3) {
+		case 0, 1:
+			_, ok := reference[key]
+			if m.Put(key, key*3) == ok {
+				t.Error("incorrect put", key)
+			}
+			reference[key] = key * 3
+		case 2:
+			_, ok := reference[key]
+			if m.Delete(key) != ok {
+				t.Error("incorrect delete", key)
+			}
+			delete(reference, key)
+		}
+	}
+	if m.Len() != len(reference) {
+		t.Error("incorrect length", m.Len(), len(reference))
+	}
+	for key, value := range reference {
+		if v, ok := m.Get(key); !ok || v != value {
+			t.Error("incorrect value", key, v, ok)
+		}
+	}
+}